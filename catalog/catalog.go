@@ -0,0 +1,316 @@
+// Package catalog 是金豆商城的兑换商品目录：按场次从商城拉取商品列表（标题、activityId、
+// 所需金豆与库存状态），当天缓存到本地；拉取失败时使用用户提供的目录文件
+package catalog
+
+import (
+	"HighFrequencyTrading/endpoint"
+	"HighFrequencyTrading/sign"
+	"HighFrequencyTrading/util"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ListPath 商城按场次查询兑换商品的接口
+const ListPath = "/gateway/standExchange/detailNew/list"
+
+// 目录来源
+const (
+	SourceMall = "mall" // 当天从商城拉取
+	SourceFile = "file" // 用户提供的目录文件
+)
+
+// StockStatus 库存状态
+type StockStatus string
+
+const (
+	StockAvailable StockStatus = "available"
+	StockSoldOut   StockStatus = "sold_out"
+	StockUnknown   StockStatus = "unknown" // 目录文件中的商品，或商城未返回库存
+)
+
+// Item 一个可兑换的商品
+type Item struct {
+	Title      string      `json:"title"`
+	ActivityID string      `json:"activityId"`
+	Price      int         `json:"price"` // 所需金豆，0 表示未知
+	Status     StockStatus `json:"status"`
+}
+
+// Catalog 各场次的商品目录
+type Catalog struct {
+	Date     string            `json:"date"`     // 拉取日期，如 "20250301"
+	Source   string            `json:"source"`   // SourceMall / SourceFile
+	Sessions map[string][]Item `json:"sessions"` // 场次名 -> 商品
+}
+
+// Items 场次的商品
+func (c *Catalog) Items(session string) []Item {
+	if c == nil {
+		return nil
+	}
+	return c.Sessions[session]
+}
+
+// Find 在场次中按标题查找商品
+func (c *Catalog) Find(session, title string) (Item, bool) {
+	for _, it := range c.Items(session) {
+		if it.Title == title {
+			return it, true
+		}
+	}
+	return Item{}, false
+}
+
+// Affordable 按 titles 的顺序在 balance 金豆内挑选商品，返回挑中的与因金豆不足跳过的标题；
+// 目录中没有价格的商品不占预算，始终挑中
+func (c *Catalog) Affordable(session string, titles []string, balance int) (picked, skipped []string) {
+	left := balance
+	for _, title := range titles {
+		it, _ := c.Find(session, title)
+		if it.Price > left {
+			skipped = append(skipped, title)
+			continue
+		}
+		left -= it.Price
+		picked = append(picked, title)
+	}
+	return picked, skipped
+}
+
+// Options Load 的参数
+type Options struct {
+	CacheFile    string            // 当天目录的缓存文件
+	FallbackFile string            // 用户提供的目录文件，拉取失败时使用，可为空
+	Sessions     map[string]string // 需要的场次：场次名 -> 开场时间 HH:MM:SS
+}
+
+// Load 返回 opts.Sessions 的商品目录：当天的缓存已包含全部场次时直接使用，
+// 否则从商城拉取缺少的场次并写回缓存；拉取失败时退回目录文件。
+// 往日的缓存中活动 ID 可能已经变化，不作为退路
+func Load(opts Options, now time.Time) (*Catalog, error) {
+	today := now.Format("20060102")
+	cached, err := ReadCache(opts.CacheFile)
+	if err != nil {
+		log.Printf("[Catalog] 读取缓存失败: %v", err)
+	}
+	if cached != nil && cached.Date == today && cached.Source == SourceMall && covers(cached, opts.Sessions) {
+		return cached, nil
+	}
+
+	cat := &Catalog{Date: today, Source: SourceMall, Sessions: make(map[string][]Item)}
+	if cached != nil && cached.Date == today && cached.Source == SourceMall {
+		for name, items := range cached.Sessions {
+			cat.Sessions[name] = items
+		}
+	}
+	fetchErr := func() error {
+		for name, start := range opts.Sessions {
+			if _, ok := cat.Sessions[name]; ok {
+				continue
+			}
+			items, err := Fetch(start)
+			if err != nil {
+				return fmt.Errorf("拉取场次 %s 的商品失败: %w", name, err)
+			}
+			cat.Sessions[name] = items
+		}
+		return nil
+	}()
+	if fetchErr == nil {
+		if err := writeCache(opts.CacheFile, cat); err != nil {
+			log.Printf("[Catalog] 保存缓存失败: %v", err)
+		}
+		return cat, nil
+	}
+
+	if opts.FallbackFile != "" {
+		log.Printf("[Catalog] %v，使用目录文件 %s", fetchErr, opts.FallbackFile)
+		return LoadFile(opts.FallbackFile, opts.Sessions)
+	}
+	return nil, fetchErr
+}
+
+func covers(c *Catalog, sessions map[string]string) bool {
+	for name := range sessions {
+		if _, ok := c.Sessions[name]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// listReply 商品列表接口的响应
+type listReply struct {
+	Code json.Number `json:"code"`
+	Msg  string      `json:"msg"`
+	Biz  struct {
+		List []struct {
+			Title      string `json:"title"`
+			ActivityID string `json:"activityId"`
+			Price      int    `json:"price"`
+			Stock      *int   `json:"stock"`
+		} `json:"list"`
+	} `json:"biz"`
+}
+
+// Fetch 从商城拉取在 start（HH:MM:SS）开场的兑换商品
+func Fetch(start string) ([]Item, error) {
+	body, err := json.Marshal(map[string]string{"startTime": start})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, endpoint.Mall(ListPath), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json;charset=UTF-8")
+	req.Header.Set("User-Agent", sign.CurrentProtocol().WebUserAgent)
+	req.Header.Set("Referer", sign.MallReferer())
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	var reply listReply
+	if err := json.Unmarshal(data, &reply); err != nil {
+		return nil, fmt.Errorf("响应无法解析: %v", err)
+	}
+	if reply.Code.String() != "0" {
+		return nil, fmt.Errorf("code=%s %s", reply.Code, reply.Msg)
+	}
+
+	items := make([]Item, 0, len(reply.Biz.List))
+	for _, it := range reply.Biz.List {
+		if it.Title == "" || it.ActivityID == "" {
+			continue
+		}
+		status := StockUnknown
+		if it.Stock != nil {
+			status = StockAvailable
+			if *it.Stock <= 0 {
+				status = StockSoldOut
+			}
+		}
+		items = append(items, Item{Title: it.Title, ActivityID: it.ActivityID, Price: it.Price, Status: status})
+	}
+	return items, nil
+}
+
+// File 用户提供的目录文件（YAML 或 JSON）
+type File struct {
+	Items []FileItem `yaml:"items" json:"items"`
+}
+
+// FileItem 目录文件中的一个商品
+type FileItem struct {
+	Title      string   `yaml:"title" json:"title"`
+	ActivityID string   `yaml:"activityId" json:"activityId"`
+	Price      int      `yaml:"price,omitempty" json:"price,omitempty"`       // 所需金豆
+	Sessions   []string `yaml:"sessions,omitempty" json:"sessions,omitempty"` // 适用的场次名，省略时适用于所有场次
+}
+
+// ReadFile 读取并校验目录文件
+func ReadFile(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f File
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("解析目录文件 %s 失败: %w", path, err)
+	}
+	var errs []error
+	for i, it := range f.Items {
+		if it.Title == "" || it.ActivityID == "" {
+			errs = append(errs, fmt.Errorf("items[%d] 缺少 title 或 activityId", i))
+		}
+		if it.Price < 0 {
+			errs = append(errs, fmt.Errorf("items[%d].price 不能为负数", i))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("目录文件 %s 无效: %w", path, errors.Join(errs...))
+	}
+	return &f, nil
+}
+
+// LoadFile 由目录文件构造 sessions 的商品目录
+func LoadFile(path string, sessions map[string]string) (*Catalog, error) {
+	f, err := ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cat := &Catalog{Source: SourceFile, Sessions: make(map[string][]Item)}
+	for name := range sessions {
+		cat.Sessions[name] = []Item{}
+		for _, it := range f.Items {
+			if len(it.Sessions) > 0 && !contains(it.Sessions, name) {
+				continue
+			}
+			cat.Sessions[name] = append(cat.Sessions[name], Item{
+				Title:      it.Title,
+				ActivityID: it.ActivityID,
+				Price:      it.Price,
+				Status:     StockUnknown,
+			})
+		}
+	}
+	return cat, nil
+}
+
+func contains(arr []string, s string) bool {
+	for _, v := range arr {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadCache 读取缓存的目录，文件不存在时返回 nil
+func ReadCache(path string) (*Catalog, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var c Catalog
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func writeCache(path string, c *Catalog) error {
+	if path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return util.WriteFileAtomic(path, data, 0600)
+}
@@ -0,0 +1,121 @@
+package catalog
+
+import (
+	"HighFrequencyTrading/endpoint"
+	"HighFrequencyTrading/internal/fakect"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadFetchesAndCaches(t *testing.T) {
+	ct := fakect.New()
+	defer ct.Close()
+	original := endpoint.Get()
+	endpoint.Set(ct.Endpoints())
+	defer endpoint.Set(original)
+	ct.AddItem("10:00:00", "5元话费", "aid_5", 500, 3)
+	ct.AddItem("10:00:00", "10元话费", "aid_10", 1000, 0)
+	ct.AddItem("14:00:00", "1元话费", "aid_1", 100, 1)
+
+	opts := Options{
+		CacheFile: filepath.Join(t.TempDir(), "catalog.json"),
+		Sessions:  map[string]string{"10": "10:00:00"},
+	}
+	now := time.Date(2025, 3, 1, 9, 0, 0, 0, time.Local)
+	cat, err := Load(opts, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Item{
+		{Title: "5元话费", ActivityID: "aid_5", Price: 500, Status: StockAvailable},
+		{Title: "10元话费", ActivityID: "aid_10", Price: 1000, Status: StockSoldOut},
+	}
+	if got := cat.Items("10"); len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("Items = %+v", got)
+	}
+
+	// 当天再次加载使用缓存；新增的场次单独拉取并合并
+	ct.Close()
+	if _, err := Load(opts, now.Add(time.Hour)); err != nil {
+		t.Fatalf("当天应使用缓存: %v", err)
+	}
+	opts.Sessions["14"] = "14:00:00"
+	if _, err := Load(opts, now); err == nil {
+		t.Error("缓存缺少场次且商城不可用时应报错")
+	}
+}
+
+func TestLoadFallback(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusBadGateway)
+	}))
+	defer ts.Close()
+	original := endpoint.Get()
+	endpoint.Set(endpoint.Endpoints{Mall: ts.URL})
+	defer endpoint.Set(original)
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "catalog.yaml")
+	err := os.WriteFile(file, []byte(`
+items:
+  - title: "5元话费"
+    activityId: "A5"
+    price: 500
+  - title: "10元话费"
+    activityId: "A10"
+    sessions: ["14"]
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := Options{
+		CacheFile:    filepath.Join(dir, "catalog.json"),
+		FallbackFile: file,
+		Sessions:     map[string]string{"10": "10:00:00", "14": "14:00:00"},
+	}
+	cat, err := Load(opts, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cat.Source != SourceFile || len(cat.Items("10")) != 1 || len(cat.Items("14")) != 2 {
+		t.Errorf("目录文件未按场次展开: %+v", cat)
+	}
+	if it, ok := cat.Find("14", "10元话费"); !ok || it.ActivityID != "A10" || it.Status != StockUnknown {
+		t.Errorf("Find = %+v, %v", it, ok)
+	}
+
+	// 没有目录文件时不使用往日缓存
+	yesterday := &Catalog{Date: "20000101", Source: SourceMall, Sessions: map[string][]Item{"10": {{Title: "5元话费", ActivityID: "OLD"}}}}
+	if err := writeCache(opts.CacheFile, yesterday); err != nil {
+		t.Fatal(err)
+	}
+	cat, err = Load(Options{CacheFile: opts.CacheFile, Sessions: map[string]string{"10": "10:00:00"}}, time.Now())
+	if err == nil {
+		t.Errorf("拉取失败且没有目录文件时应报错，不应使用往日缓存: %+v", cat)
+	}
+
+	if err := os.WriteFile(file, []byte("items:\n  - title: x\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadFile(file); err == nil {
+		t.Error("缺少 activityId 的目录文件应报错")
+	}
+}
+
+func TestAffordable(t *testing.T) {
+	cat := &Catalog{Sessions: map[string][]Item{"10": {
+		{Title: "10元话费", ActivityID: "A10", Price: 1000},
+		{Title: "5元话费", ActivityID: "A5", Price: 500},
+		{Title: "1元话费", ActivityID: "A1", Price: 100},
+		{Title: "3元话费", ActivityID: "A3"},
+	}}}
+	picked, skipped := cat.Affordable("10", []string{"5元话费", "10元话费", "1元话费", "3元话费"}, 700)
+	if strings.Join(picked, ",") != "5元话费,1元话费,3元话费" || strings.Join(skipped, ",") != "10元话费" {
+		t.Errorf("picked=%v skipped=%v", picked, skipped)
+	}
+}
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"HighFrequencyTrading/catalog"
+	"HighFrequencyTrading/config"
+	"HighFrequencyTrading/exchange"
+	"encoding/json"
+	"fmt"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+	"io"
+	"text/tabwriter"
+)
+
+var (
+	configCmd = &cobra.Command{
+		Use:   "config",
+		Short: "配置管理",
+	}
+
+	showEffective bool
+	showOutput    string
+
+	configShowCmd = &cobra.Command{
+		Use:   "show",
+		Short: "显示配置（密码、令牌已脱敏）",
+		Long: `默认显示配置文件内容；--effective 显示合并 命令行参数 / 环境变量 / 配置文件 / 默认值 后
+最终生效的配置，并标注每一项的来源`,
+		Example:      `telecom config show --effective -o json`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if showOutput != "text" && showOutput != "json" {
+				return fmt.Errorf("不支持的输出格式 %q，可选 text / json", showOutput)
+			}
+			cfg, err := config.NewConfig(rootOptions())
+			if err != nil {
+				return err
+			}
+			out := cmd.OutOrStdout()
+
+			if !showEffective {
+				if cfg.ConfigFile == "" {
+					fmt.Fprintln(out, "未使用配置文件，可使用 --effective 查看最终生效的配置")
+					return nil
+				}
+				fc, err := config.LoadFile(cfg.ConfigFile)
+				if err != nil {
+					return err
+				}
+				if showOutput == "json" {
+					return writeJSON(out, fc.Masked())
+				}
+				fmt.Fprintf(out, "# %s\n", cfg.ConfigFile)
+				enc := yaml.NewEncoder(out)
+				enc.SetIndent(2)
+				return enc.Encode(fc.Masked())
+			}
+
+			values := cfg.Effective()
+			if showOutput == "json" {
+				return writeJSON(out, values)
+			}
+			w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "KEY\tVALUE\tSOURCE")
+			for _, v := range values {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", v.Key, v.Value, v.Source)
+			}
+			return w.Flush()
+		},
+	}
+
+	configValidateCmd = &cobra.Command{
+		Use:          "validate",
+		Short:        "校验账号与兑换策略",
+		Long:         "深度校验手机号、密码长度、交易时段、MEXZ 场次与商品，列出所有问题并以非零状态退出",
+		Example:      `telecom config validate --config telecom.yaml`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.NewConfig(rootOptions())
+			if err != nil {
+				return err
+			}
+
+			problems := config.Validate(cfg, tradeItemTitles(cfg))
+			out := cmd.OutOrStdout()
+			for _, p := range problems {
+				fmt.Fprintf(out, "✗ %s\n", p)
+			}
+			if len(problems) > 0 {
+				return fmt.Errorf("配置校验失败，共 %d 个问题", len(problems))
+			}
+			fmt.Fprintf(out, "✓ 配置有效: %d 个账号, MEXZ=%s\n", len(cfg.Accounts), cfg.MEXZ)
+			return nil
+		},
+	}
+)
+
+// defaultItemTitles 商城常见的话费商品，校验配置时不联网拉取商品列表
+var defaultItemTitles = []string{"0.5元话费", "5元话费", "6元话费", "1元话费", "10元话费", "3元话费"}
+
+// tradeItemTitles 返回可兑换商品标题：常见话费商品，加上目录文件与当天缓存中出现的商品
+func tradeItemTitles(cfg *config.Config) []string {
+	titles := append([]string(nil), defaultItemTitles...)
+	add := func(title string) {
+		if !exchange.InStringArray(title, titles) {
+			titles = append(titles, title)
+		}
+	}
+	if cfg.CatalogFile != "" {
+		if f, err := catalog.ReadFile(cfg.CatalogFile); err == nil {
+			for _, it := range f.Items {
+				add(it.Title)
+			}
+		}
+	}
+	if cached, err := catalog.ReadCache(cfg.Paths.Catalog()); err == nil && cached != nil {
+		for _, items := range cached.Sessions {
+			for _, it := range items {
+				add(it.Title)
+			}
+		}
+	}
+	return titles
+}
+
+// writeJSON 以缩进格式输出 JSON
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func init() {
+	configShowCmd.Flags().BoolVar(&showEffective, "effective", false, "显示最终生效的配置及来源")
+	configShowCmd.Flags().StringVarP(&showOutput, "output", "o", "text", "输出格式: text / json")
+	configCmd.AddCommand(configShowCmd, configValidateCmd)
+}
@@ -0,0 +1,267 @@
+package cmd
+
+import (
+	"HighFrequencyTrading/catalog"
+	"HighFrequencyTrading/config"
+	"HighFrequencyTrading/endpoint"
+	"HighFrequencyTrading/internal/fakect"
+	"HighFrequencyTrading/ledger"
+	"HighFrequencyTrading/store"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestMainLogicEndToEnd 对着进程内的模拟服务跑完整的一场：登录、换取 ticket、定时兑换、写账本、推送汇总
+func TestMainLogicEndToEnd(t *testing.T) {
+	if testing.Short() {
+		t.Skip("需要等待开场，-short 时跳过")
+	}
+	ct := fakect.New()
+	defer ct.Close()
+	defer endpoint.Set(endpoint.Get())
+	ct.AddAccount("13800138000", "123456")
+	ct.AddAccount("13900139000", "654321")
+	ct.AddItem("", "5元话费", "aid_5", 500, 10)
+	ct.AddItem("", "10元话费", "aid_10", 1000, 0)
+	// 第二个账号的金豆不够兑换 5 元话费
+	ct.SetBeans("13900139000", 300)
+
+	// 场次在 2 秒后开场，开场时间精确到秒
+	opens := time.Now().Add(2 * time.Second).Truncate(time.Second)
+	ct.OpenAt(opens)
+
+	cfg := e2eConfig(t, ct, opens, false)
+	MainLogic(cfg)
+
+	// 金豆足够的账号在开场后发出一次兑换，所有请求都携带各自的商城登录态
+	var attempts int
+	for _, ex := range ct.Exchanges() {
+		if ex.ActivityID != "aid_5" {
+			t.Errorf("兑换了未配置的活动 %q", ex.ActivityID)
+		}
+		if ex.Phone == "" {
+			t.Errorf("兑换请求未携带商城登录态: %+v", ex)
+		}
+		if !ex.Time.Before(opens) {
+			attempts++
+		}
+	}
+	if attempts != 1 {
+		t.Errorf("开场后收到 %d 次兑换，期望 1", attempts)
+	}
+	if ct.Granted("13800138000", "aid_5") != 1 {
+		t.Error("13800138000 未兑换成功")
+	}
+	if ct.Granted("13900139000", "aid_5") != 0 {
+		t.Error("金豆不足的账号不应兑换")
+	}
+
+	// 汇总中带有兑换前后的金豆余额
+	msgs := ct.Messages()
+	if len(msgs) == 0 {
+		t.Fatal("未推送汇总")
+	}
+	summary := msgs[len(msgs)-1]
+	for _, want := range []string{"13800138000 10000 → 9500", "13900139000 300"} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("汇总中缺少 %q:\n%s", want, summary)
+		}
+	}
+
+	// 商品目录取自商城并缓存到当天
+	cat, err := catalog.ReadCache(cfg.Paths.Catalog())
+	if err != nil || cat == nil {
+		t.Fatalf("未缓存商品目录: %v", err)
+	}
+	if it, ok := cat.Find("e2e", "5元话费"); !ok || it.ActivityID != "aid_5" || it.Price != 500 {
+		t.Errorf("商品目录不符: %+v", cat)
+	}
+
+	st, err := store.Open(cfg.Storage, cfg.Paths.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+
+	// 两个账号都登录成功并缓存了 ticket
+	cache, err := st.Cache()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, phone := range []string{"13800138000", "13900139000"} {
+		if cache[phone].Ticket == "" || cache[phone].IssuedAt.IsZero() {
+			t.Errorf("%s 未缓存 ticket: %+v", phone, cache[phone])
+		}
+	}
+
+	// 账本逐次记录兑换尝试，业务码与模拟服务的返回一致
+	records, err := st.Records()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("账本中有 %d 条记录，期望 1: %+v", len(records), records)
+	}
+	codes := make(map[string]bool)
+	for _, ex := range ct.Exchanges() {
+		codes[ex.Code] = true
+	}
+	for _, r := range records {
+		if r.Session != "e2e" || r.Item != "5元话费" || r.ActivityID != "aid_5" || r.HTTPStatus != 200 || !codes[r.Code] || r.Outcome != ledger.OutcomeSuccess {
+			t.Errorf("账本记录不符: %+v", r)
+		}
+	}
+
+	runs, err := st.Runs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 1 || runs[0].FinishedAt.IsZero() || runs[0].Attempts != 1 {
+		t.Errorf("运行历史不符: %+v", runs)
+	}
+}
+
+// e2eConfig 在临时数据目录中写入指向模拟服务的配置：两个账号，场次 e2e 在 opens 开场，兑换 5 元话费
+func e2eConfig(t *testing.T, ct *fakect.Server, opens time.Time, dryRun bool) *config.Config {
+	return e2eConfigMall(t, ct, opens, dryRun, ct.URL)
+}
+
+// e2eConfigMall 同 e2eConfig，商城接口使用 mall 地址
+func e2eConfigMall(t *testing.T, ct *fakect.Server, opens time.Time, dryRun bool, mall string) *config.Config {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("TELECOM_DATA_DIR", dir)
+	t.Setenv("jdhf", "")
+	t.Setenv("MEXZ", "")
+	t.Setenv("CTIME", "")
+	t.Setenv("TELECOM_SESSION", "")
+	t.Setenv("WXPUSHER_APP_TOKEN", "AT_test")
+	t.Setenv("WXPUSHER_UID", "UID_test")
+	path := filepath.Join(dir, "telecom.yaml")
+	err := os.WriteFile(path, []byte(fmt.Sprintf(`
+accounts:
+  - phone: "13800138000"
+    password: "123456"
+  - phone: "13900139000"
+    password: "654321"
+strategy:
+  session: e2e
+  sessions:
+    - name: e2e
+      start: "%s"
+      items: ["5"]
+endpoints:
+  login: %[2]s
+  ticket: %[2]s
+  mall: %[3]s
+  wxpusher: %[2]s
+`, opens.Format("15:04:05"), ct.URL, mall)), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := config.NewConfig(config.Options{ConfigFile: path, DryRun: dryRun})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+// TestMainLogicDryRun 演练走完登录、调度与预热，但兑换请求不到达商城，也不写账本与运行历史
+func TestMainLogicDryRun(t *testing.T) {
+	if testing.Short() {
+		t.Skip("需要等待开场，-short 时跳过")
+	}
+	ct := fakect.New()
+	defer ct.Close()
+	defer endpoint.Set(endpoint.Get())
+	ct.AddAccount("13800138000", "123456")
+	ct.AddAccount("13900139000", "654321")
+	ct.AddItem("", "10元话费", "aid_10", 1000, 10)
+	ct.AddItem("", "5元话费", "aid_5", 500, 10)
+
+	opens := time.Now().Add(2 * time.Second).Truncate(time.Second)
+	ct.OpenAt(opens)
+	cfg := e2eConfig(t, ct, opens, true)
+	// 偏好模式：演练中 10元话费即兑到，5元话费不会尝试，也不应出现在计划中
+	cfg.Sessions[0].Items = []string{"10", "5"}
+	cfg.Sessions[0].MaxItems = 1
+	var timeline strings.Builder
+	defer func(w io.Writer) { timelineOut = w }(timelineOut)
+	timelineOut = &timeline
+	MainLogic(cfg)
+
+	for _, want := range []string{"13800138000", "13900139000", "抢发", "预热", "兑换 10元话费", "success"} {
+		if !strings.Contains(timeline.String(), want) {
+			t.Errorf("时间线中缺少 %q:\n%s", want, timeline.String())
+		}
+	}
+	if strings.Contains(timeline.String(), "5元话费") {
+		t.Errorf("时间线不应列出未尝试的 5元话费:\n%s", timeline.String())
+	}
+
+	if exs := ct.Exchanges(); len(exs) != 0 {
+		t.Errorf("演练不应向商城发出兑换请求: %+v", exs)
+	}
+	if ct.Beans("13800138000") != fakect.DefaultBeans {
+		t.Error("演练不应扣减金豆")
+	}
+	if msgs := ct.Messages(); len(msgs) != 0 {
+		t.Errorf("演练不应推送汇总: %v", msgs)
+	}
+
+	st, err := store.Open(cfg.Storage, cfg.Paths.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	// 登录照常进行并缓存 ticket
+	cache, err := st.Cache()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cache["13800138000"].Ticket == "" {
+		t.Error("演练时应照常登录并缓存 ticket")
+	}
+	if records, err := st.Records(); err != nil || len(records) != 0 {
+		t.Errorf("演练不应写账本: %+v, %v", records, err)
+	}
+	if runs, err := st.Runs(); err != nil || len(runs) != 0 {
+		t.Errorf("演练不应写运行历史: %+v, %v", runs, err)
+	}
+}
+
+// TestMainLogicDryRunMallPrefix 商城地址带路径前缀时，演练的兑换请求同样不到达商城
+func TestMainLogicDryRunMallPrefix(t *testing.T) {
+	if testing.Short() {
+		t.Skip("需要等待开场，-short 时跳过")
+	}
+	ct := fakect.New()
+	defer ct.Close()
+	defer endpoint.Set(endpoint.Get())
+	ct.AddAccount("13800138000", "123456")
+	ct.AddAccount("13900139000", "654321")
+	ct.AddItem("", "5元话费", "aid_5", 500, 10)
+
+	opens := time.Now().Add(2 * time.Second).Truncate(time.Second)
+	cfg := e2eConfigMall(t, ct, opens, true, ct.URL+fakect.PathPrefix)
+	var timeline strings.Builder
+	defer func(w io.Writer) { timelineOut = w }(timelineOut)
+	timelineOut = &timeline
+	MainLogic(cfg)
+
+	if exs := ct.Exchanges(); len(exs) != 0 {
+		t.Errorf("演练不应向商城发出兑换请求: %+v", exs)
+	}
+	if ct.Beans("13800138000") != fakect.DefaultBeans {
+		t.Error("演练不应扣减金豆")
+	}
+	if !strings.Contains(timeline.String(), "兑换 5元话费") || !strings.Contains(timeline.String(), "success") {
+		t.Errorf("时间线中缺少演练的兑换:\n%s", timeline.String())
+	}
+}
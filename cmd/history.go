@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"HighFrequencyTrading/config"
+	"HighFrequencyTrading/ledger"
+	"HighFrequencyTrading/store"
+	"encoding/csv"
+	"fmt"
+	"github.com/spf13/cobra"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+var (
+	historyMonth  string
+	historyPhone  string
+	historyItem   string
+	historyOutput string
+
+	historyCmd = &cobra.Command{
+		Use:   "history",
+		Short: "查询兑换记录（含已归档的月份）",
+		Example: `telecom history --month 202503
+telecom history --phone 13800138000 --item 5 -o csv > history.csv`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			switch historyOutput {
+			case "table", "json", "csv":
+			default:
+				return fmt.Errorf("不支持的输出格式 %q，可选 table / json / csv", historyOutput)
+			}
+			if historyMonth != "" {
+				if _, err := time.Parse("200601", historyMonth); err != nil {
+					return fmt.Errorf("月份 %q 格式错误，应为 YYYYMM", historyMonth)
+				}
+			}
+			item := historyItem
+			if item != "" {
+				item = config.ItemTitle(item)
+			}
+
+			cfg, err := config.NewConfig(rootOptions())
+			if err != nil {
+				return err
+			}
+			st, err := cfg.OpenStore()
+			if err != nil {
+				return err
+			}
+			defer st.Close()
+			records, err := loadHistory(st, cfg.Paths.ArchiveDir(), historyMonth)
+			if err != nil {
+				return err
+			}
+			records = ledger.Filter(records, historyMonth, historyPhone, item)
+
+			out := cmd.OutOrStdout()
+			switch historyOutput {
+			case "json":
+				if records == nil {
+					records = []ledger.Record{}
+				}
+				return writeJSON(out, records)
+			case "csv":
+				return writeHistoryCSV(out, records)
+			default:
+				return writeHistoryTable(out, records)
+			}
+		},
+	}
+
+	historyArchiveCmd = &cobra.Command{
+		Use:   "archive",
+		Short: "立即按保留策略归档、清理兑换记录",
+		Long: `保留策略由配置文件的 retention 指定（月份均包含当月）：
+  keepMonths   账本中保留最近几个月，更早的移入数据目录下 ` + store.ArchiveDir + `/ 中的按月文件（默认 ` + strconv.Itoa(config.DefaultKeepMonths) + `）
+  pruneMonths  最多保留最近几个月（含归档），更早的删除（默认 0，永久保留）
+每次运行兑换时也会自动执行。`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.NewConfig(rootOptions())
+			if err != nil {
+				return err
+			}
+			st, err := cfg.OpenStore()
+			if err != nil {
+				return err
+			}
+			defer st.Close()
+			res, err := store.ApplyRetention(st, cfg.Paths.ArchiveDir(), cfg.KeepMonths, cfg.PruneMonths, time.Now())
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "已归档 %d 条记录 %v，删除 %d 条记录\n", res.Archived, res.ArchivedMonths, res.Pruned)
+			return nil
+		},
+	}
+)
+
+// loadHistory 读取存储中的记录与归档记录；指定月份时只读取该月的归档
+func loadHistory(st store.Store, archiveDir, month string) ([]ledger.Record, error) {
+	records, err := st.Records()
+	if err != nil {
+		return nil, err
+	}
+	months := []string{month}
+	if month == "" {
+		if months, err = store.ArchivedMonths(archiveDir); err != nil {
+			return nil, err
+		}
+	}
+	for _, m := range months {
+		archived, err := store.ArchivedRecords(archiveDir, m)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, archived...)
+	}
+	return records, nil
+}
+
+var historyHeader = []string{"TIME", "SESSION", "PHONE", "ITEM", "ACTIVITY", "OUTCOME", "HTTP", "CODE", "MESSAGE", "LATENCY_MS"}
+
+func historyRow(r ledger.Record) []string {
+	status := ""
+	if r.HTTPStatus != 0 {
+		status = strconv.Itoa(r.HTTPStatus)
+	}
+	return []string{
+		r.Time.Format("2006-01-02 15:04:05.000"), r.Session, r.Phone, r.Item, r.ActivityID,
+		r.Outcome, status, r.Code, r.Message, strconv.FormatInt(r.LatencyMs, 10),
+	}
+}
+
+func writeHistoryTable(out io.Writer, records []ledger.Record) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	writeRow := func(cols []string) {
+		for i, c := range cols {
+			if i > 0 {
+				fmt.Fprint(w, "\t")
+			}
+			fmt.Fprint(w, c)
+		}
+		fmt.Fprintln(w)
+	}
+	writeRow(historyHeader)
+	for _, r := range records {
+		writeRow(historyRow(r))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(out, "共 %d 条记录\n", len(records))
+	return err
+}
+
+func writeHistoryCSV(out io.Writer, records []ledger.Record) error {
+	w := csv.NewWriter(out)
+	if err := w.Write(historyHeader); err != nil {
+		return err
+	}
+	for _, r := range records {
+		if err := w.Write(historyRow(r)); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func init() {
+	historyCmd.Flags().StringVar(&historyMonth, "month", "", "只显示该月的记录，格式 YYYYMM")
+	historyCmd.Flags().StringVar(&historyPhone, "phone", "", "只显示该手机号的记录")
+	historyCmd.Flags().StringVar(&historyItem, "item", "", "只显示该商品的记录，如 5 或 5元话费")
+	historyCmd.Flags().StringVarP(&historyOutput, "output", "o", "table", "输出格式: table / json / csv")
+	historyCmd.AddCommand(historyArchiveCmd)
+}
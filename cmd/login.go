@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"HighFrequencyTrading/config"
+	"HighFrequencyTrading/sign"
+	"HighFrequencyTrading/store"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"time"
+)
+
+var loginCmd = &cobra.Command{
+	Use:   "login [phone...]",
+	Short: "按账号配置的登录方式登录并缓存 ticket",
+	Long: `按每个账号的 auth 配置登录（password / sms / ticket），将 ticket 写入缓存供后续运行使用。
+短信验证码登录需要交互输入，建议在开场前执行本命令，避免定时任务中无人输入验证码。
+手动登录不受失败退避限制，成功后清除该账号的失败记录。
+不指定手机号时处理所有启用的账号。`,
+	Example:      `telecom login 13800138000`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.NewConfig(rootOptions())
+		if err != nil {
+			return err
+		}
+		accounts, err := selectAccounts(cfg, args)
+		if err != nil {
+			return err
+		}
+		g, err := config.InitGlobalVars(cfg)
+		if err != nil {
+			return err
+		}
+		defer g.Close()
+
+		codes := sign.NewPrompter(cmd.InOrStdin(), cmd.ErrOrStderr())
+		failed := 0
+		for _, acc := range accounts {
+			auth, err := acc.Authenticator(codes)
+			if err == nil {
+				var ticket string
+				if ticket, err = auth.Login(acc.Phone); err == nil {
+					now := time.Now()
+					err = g.PutCache(acc.Phone, store.Ticket{Ticket: ticket, IssuedAt: now, ValidatedAt: now})
+					if err == nil {
+						err = g.RecordLoginSuccess(acc.Phone)
+					}
+				} else if _, suspended, recErr := g.RecordLoginFailure(acc, err, time.Now()); recErr == nil && suspended {
+					err = fmt.Errorf("%w，已暂停自动登录，修改配置中的凭证后恢复", err)
+				}
+			}
+			if err != nil {
+				failed++
+				fmt.Fprintf(cmd.OutOrStdout(), "✗ %s: %v\n", acc.Phone, err)
+				continue
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "✓ %s 登录成功 (%s)\n", acc.Phone, auth.Method())
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d 个账号登录失败", failed)
+		}
+		return nil
+	},
+}
+
+// selectAccounts 按手机号挑选账号，未指定时返回所有启用的账号
+func selectAccounts(cfg *config.Config, phones []string) ([]config.Account, error) {
+	if len(phones) == 0 {
+		accounts := cfg.EnabledAccounts()
+		if len(accounts) == 0 {
+			return nil, errors.New("未检测到账号信息")
+		}
+		return accounts, nil
+	}
+	var res []config.Account
+	for _, phone := range phones {
+		found := false
+		for _, acc := range cfg.Accounts {
+			if acc.Phone == phone {
+				res = append(res, acc)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("配置中没有账号 %s", phone)
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"HighFrequencyTrading/config"
+	"fmt"
+	"github.com/spf13/cobra"
+	"text/tabwriter"
+)
+
+var (
+	profilesCmd = &cobra.Command{
+		Use:   "profiles",
+		Short: "管理 profile（独立的数据目录）",
+	}
+
+	profilesListCmd = &cobra.Command{
+		Use:          "list",
+		Short:        "列出所有 profile",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root, err := config.DataRoot()
+			if err != nil {
+				return err
+			}
+			current, err := config.ResolvePaths(rootOptions())
+			if err != nil {
+				return err
+			}
+			profiles, err := config.ListProfiles()
+			if err != nil {
+				return err
+			}
+			if len(profiles) == 0 {
+				fmt.Fprintf(cmd.OutOrStdout(), "%s 下尚无 profile\n", root)
+				return nil
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "\tPROFILE\tCONFIG\tUPDATED\tDIR")
+			for _, p := range profiles {
+				mark := ""
+				if p.Dir == current.Dir {
+					mark = "*"
+				}
+				hasConfig := "-"
+				if p.HasConfig {
+					hasConfig = "yes"
+				}
+				updated := "-"
+				if !p.Modified.IsZero() {
+					updated = p.Modified.Format("2006-01-02 15:04")
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", mark, p.Name, hasConfig, updated, p.Dir)
+			}
+			return w.Flush()
+		},
+	}
+)
+
+func init() {
+	profilesCmd.AddCommand(profilesListCmd)
+}
@@ -4,27 +4,46 @@ import (
 	"HighFrequencyTrading/config"
 	"fmt"
 	"github.com/spf13/cobra"
+	"io"
+	"log"
+	"os"
 )
 
 var (
+	profileFlag  string
+	dataDirFlag  string
+	configFlag   string
 	jdhfFlag     string
 	mexzFlag     string
 	hFlag        int
+	sessionFlag  string
 	useTradeHour bool
+	dryRun       bool
 
 	rootCmd = &cobra.Command{
 		Use:   "telecom",
 		Short: "电信金豆换话费",
-		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-			// 调用交易逻辑
-			return RunMain(jdhfFlag, mexzFlag, useTradeHourToH())
-		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return nil
+			// 调用交易逻辑（仅根命令执行，子命令不触发交易）
+			return RunMain(rootOptions())
 		},
 	}
 )
 
+// rootOptions 汇总根命令上的全局参数
+func rootOptions() config.Options {
+	return config.Options{
+		Profile:    profileFlag,
+		DataDir:    dataDirFlag,
+		ConfigFile: configFlag,
+		Jdhf:       jdhfFlag,
+		MEXZ:       mexzFlag,
+		H:          useTradeHourToH(),
+		Session:    sessionFlag,
+		DryRun:     dryRun,
+	}
+}
+
 // useTradeHourToH 根据 useTradeHour 决定是否传递交易时段参数
 func useTradeHourToH() *int {
 	if useTradeHour {
@@ -33,26 +52,67 @@ func useTradeHourToH() *int {
 	return nil
 }
 
+// formatTradeHour 打印交易时段，未设置时显示 auto
+func formatTradeHour(h *int) string {
+	if h == nil {
+		return "auto"
+	}
+	return fmt.Sprint(*h)
+}
+
+// profilePaths 解析并创建当前 profile 的数据目录
+func profilePaths() (config.Paths, error) {
+	paths, err := config.ResolvePaths(rootOptions())
+	if err != nil {
+		return config.Paths{}, err
+	}
+	return paths, paths.Ensure()
+}
+
 // Execute 为命令执行入口
 func Execute() error {
 	return rootCmd.Execute()
 }
 
 func init() {
+	rootCmd.PersistentFlags().StringVarP(&profileFlag, "profile", "p", "", "profile 名称，每个 profile 拥有独立的配置、记录、缓存与日志 (默认 "+config.DefaultProfile+")")
+	rootCmd.PersistentFlags().StringVar(&dataDirFlag, "data-dir", "", "数据目录，默认 $XDG_DATA_HOME/telecom/<profile>")
+	rootCmd.PersistentFlags().StringVarP(&configFlag, "config", "c", "", "账号与策略配置文件 (YAML/TOML)，默认使用 profile 目录下的 config.yaml")
 	rootCmd.PersistentFlags().StringVar(&jdhfFlag, "jdhf", "", "电信账号信息,格式 phone#password#Uid(&phone2#pwd2#uid2)")
-	rootCmd.PersistentFlags().StringVar(&mexzFlag, "mexz", "0.5,5;1,10", "兑换策略,如 0.5,5,6;1,10,3")
-	rootCmd.PersistentFlags().IntVar(&hFlag, "trade-hour", 0, "交易时段: 10(上午场) 或 14(下午场)")
+	rootCmd.PersistentFlags().StringVar(&mexzFlag, "mexz", "", "兑换策略,如 0.5,5,6;1,10,3 (默认 "+config.DefaultMEXZ+")")
+	rootCmd.PersistentFlags().IntVar(&hFlag, "trade-hour", 0, "交易时段: 按开场小时匹配场次，如 10(上午场) 或 14(下午场)")
+	rootCmd.PersistentFlags().StringVar(&sessionFlag, "session", "", "指定场次名，优先于 --trade-hour")
 	rootCmd.PersistentFlags().BoolVar(&useTradeHour, "use-trade-hour", false, "是否启用交易时段参数")
+	rootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "演练：照常登录、调度与预热，兑换请求只记录不发往商城，结束后输出时间线，不写兑换记录")
 
-	// 注册 wxpusher 子命令
+	// 注册子命令
 	rootCmd.AddCommand(wxpusherCmd)
+	rootCmd.AddCommand(configCmd)
+	rootCmd.AddCommand(secretsCmd)
+	rootCmd.AddCommand(profilesCmd)
+	rootCmd.AddCommand(storeCmd)
+	rootCmd.AddCommand(historyCmd)
+	rootCmd.AddCommand(loginCmd)
 }
 
 // RunMain 真正执行主交易流程
-func RunMain(cliJdhf, cliMEXZ string, cliH *int) error {
-	cfg := config.NewConfig(cliJdhf, cliMEXZ, cliH)
-	fmt.Printf("[Cobra] 最终配置: jdhf=%s, MEXZ=%s, trade-hour=%v\n",
-		cfg.Jdhf, cfg.MEXZ, cfg.H)
+func RunMain(opts config.Options) error {
+	cfg, err := config.NewConfig(opts)
+	if err != nil {
+		return err
+	}
+	// 运行日志同时写入 profile 目录
+	if f, err := cfg.Paths.OpenLogFile(); err != nil {
+		log.Printf("[Warn] 无法打开日志文件: %v", err)
+	} else {
+		defer f.Close()
+		log.SetOutput(io.MultiWriter(os.Stderr, f))
+		defer log.SetOutput(os.Stderr)
+	}
+
+	// 只打印脱敏后的摘要，完整配置请使用 telecom config show --effective
+	fmt.Printf("[Cobra] 最终配置: profile=%s, config=%s, accounts=%d, MEXZ=%s, trade-hour=%v, dry-run=%v\n",
+		cfg.Paths.Profile, cfg.ConfigFile, len(cfg.Accounts), cfg.MEXZ, formatTradeHour(cfg.H), cfg.DryRun)
 	// 调用主交易逻辑（耗时流程）
 	MainLogic(cfg)
 	return nil
@@ -2,19 +2,23 @@ package cmd
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"math"
-	"net/http"
 	"os"
 	"strconv"
-	"strings"
 	"sync"
 	"time"
 
+	"HighFrequencyTrading/catalog"
 	"HighFrequencyTrading/config"
 	"HighFrequencyTrading/exchange"
+	"HighFrequencyTrading/ledger"
 	"HighFrequencyTrading/sign"
+	"HighFrequencyTrading/store"
+	"HighFrequencyTrading/util"
 )
 
 // MainLogic 是程序入口
@@ -22,28 +26,66 @@ func MainLogic(cfg *config.Config) {
 	log.Println("===== 高频交易系统启动 =====")
 
 	// 1. 初始化全局配置
-	g := config.InitGlobalVars(cfg)
-	if cfg.Jdhf == "" {
+	g, err := config.InitGlobalVars(cfg)
+	if err != nil {
+		log.Printf("[Error] %v", err)
+		return
+	}
+	defer g.Close()
+	accounts := cfg.EnabledAccounts()
+	if len(accounts) == 0 {
 		log.Println("[Error] 未检测到账号信息，退出")
 		return
 	}
 
-	accounts := strings.Split(cfg.Jdhf, "&")
 	log.Printf("检测到 %d 个账号", len(accounts))
 
 	if cfg.H != nil {
 		log.Printf("[CMD] 强制设定 h=%d", *cfg.H)
 	}
 
-	client := &http.Client{Timeout: 5 * time.Second}
+	// 确定本次参与的场次
+	session, err := determineSession(cfg, time.Now())
+	if err != nil {
+		log.Printf("[Error] %v", err)
+		return
+	}
+	log.Printf("[Session] 场次 %s 开场时间 %s", session.Name, time.Unix(exchange.CalcT(session), 0).Format("2006-01-02 15:04:05"))
+
+	// 演练时兑换请求交给记录器，不写运行历史
+	var rec *exchange.Recorder
+	if cfg.DryRun {
+		log.Println("[DryRun] 演练模式：兑换请求只记录，不发往商城")
+		rec = exchange.NewRecorder(time.Unix(exchange.CalcT(session), 0))
+	} else {
+		// 记录运行历史，异常退出时 FinishedAt 保持为空
+		run := store.Run{
+			ID:        store.NewRunID(time.Now()),
+			Profile:   cfg.Paths.Profile,
+			Session:   session.Name,
+			StartedAt: time.Now(),
+			Accounts:  len(accounts),
+		}
+		saveRun(g, run)
+		defer finishRun(g, run)
+	}
+
+	// 获取本场的商品目录并更新到 g.Jp
+	cat, err := loadCatalog(cfg, session)
+	if err != nil {
+		log.Printf("[Error] 获取商品目录失败: %v", err)
+		return
+	}
+	log.Printf("[Catalog] 场次 %s 共 %d 个商品（来源 %s）", session.Name, len(cat.Items(session.Name)), cat.Source)
+	updateGlobalProducts(g, cat)
 
 	// 2. 并发处理每个账号
 	var wg sync.WaitGroup
 	for _, account := range accounts {
 		wg.Add(1)
-		go func(ac string) {
+		go func(ac config.Account) {
 			defer wg.Done()
-			processAccount(ac, g, client, cfg)
+			processAccount(ac, g, session, rec)
 		}(account)
 	}
 	wg.Wait()
@@ -55,6 +97,12 @@ func MainLogic(cfg *config.Config) {
 
 	waitUntilTargetTime(wt)
 
+	if rec != nil {
+		rec.WriteTimeline(timelineOut)
+		log.Println("===== 演练结束 =====")
+		return
+	}
+
 	// 4. 处理日志保存
 	handleExchangeLog(g)
 
@@ -65,161 +113,398 @@ func MainLogic(cfg *config.Config) {
 	log.Println("===== 高频交易系统结束 =====")
 }
 
-func processAccount(accountStr string, g *config.GlobalVars, client *http.Client, cfg *config.Config) {
-	// 解析账号信息
-	fields := strings.Split(accountStr, "#")
-	if len(fields) < 2 {
-		log.Printf("[Error] 账号格式错误: %s", accountStr)
+func processAccount(acc config.Account, g *config.GlobalVars, session config.Session, rec *exchange.Recorder) {
+	// 获取 ticket 并登录商城，每个账号使用独立的会话
+	sess := openMallSession(acc, g)
+	if sess == nil {
 		return
 	}
-	phone := fields[0]
-	password := fields[1]
-	uid := getUID(fields, phone)
-
-	// 获取 token
-	token := getToken(phone, password, g)
-	if token == "" {
-		return
+	if rec != nil {
+		sess.Record(rec)
 	}
 
 	// 执行交易逻辑
-	executeTrading(g, phone, token, uid, client, cfg)
+	executeTrading(g, acc, session, sess)
 }
 
-func getUID(fields []string, phone string) string {
-	if len(fields) >= 3 {
-		return fields[len(fields)-1]
+// openMallSession 用 ticket 登录商城建立会话；ticket 被商城拒绝时删除缓存并重新登录一次
+func openMallSession(acc config.Account, g *config.GlobalVars) *exchange.MallSession {
+	phone := acc.Phone
+	for attempt := 0; attempt < 2; attempt++ {
+		token := getToken(acc, g)
+		if token == "" {
+			return nil
+		}
+		sess, err := newMallSession(phone, token)
+		if err == nil {
+			log.Printf("[Session] phone=%s 商城登录成功", phone)
+			return sess
+		}
+		if !errors.Is(err, sign.ErrTicketInvalid) {
+			log.Printf("[Error] phone=%s 商城登录失败: %v", phone, err)
+			return nil
+		}
+		log.Printf("[Session] phone=%s %v，删除缓存后重新登录", phone, err)
+		if err := g.DeleteCache(phone); err != nil {
+			log.Printf("[Warn] phone=%s 删除失效缓存失败: %v", phone, err)
+		}
 	}
-	return phone
+	log.Printf("[Error] phone=%s 重新登录后商城仍拒绝 ticket", phone)
+	return nil
 }
 
-// getToken 封装缓存处理逻辑：先尝试从缓存中取 token，否则重新登录获取
-func getToken(phone, password string, g *config.GlobalVars) string {
+// smsCodes 短信验证码登录时从标准输入读取验证码，提示输出到标准错误
+var smsCodes = sign.NewPrompter(os.Stdin, os.Stderr)
+
+// 登录、ticket 校验、商城会话与告警推送，测试中可替换
+var (
+	newAuthenticator   = func(acc config.Account) (sign.Authenticator, error) { return acc.Authenticator(smsCodes) }
+	validateTicketFunc = sign.ValidateTicket
+	newMallSession     = exchange.NewMallSession
+	notifyFunc         = exchange.Notify
+	stdinIsTerminal    = isTerminal(os.Stdin)
+)
+
+// timelineOut 演练结束时输出时间线，测试中可替换
+var timelineOut io.Writer = os.Stdout
+
+// isTerminal 判断文件是否为交互终端；定时任务中标准输入通常为空或管道
+func isTerminal(f *os.File) func() bool {
+	return func() bool {
+		fi, err := f.Stat()
+		return err == nil && fi.Mode()&os.ModeCharDevice != 0
+	}
+}
+
+// 登录服务暂不可用时的重试次数与首次重试间隔，之后每次翻倍
+var (
+	loginRetries = 2
+	loginBackoff = time.Second
+)
+
+// getToken 封装缓存处理逻辑：缓存的 ticket 未过期且校验通过时直接使用，否则按账号配置的登录方式重新登录
+func getToken(acc config.Account, g *config.GlobalVars) string {
+	phone := acc.Phone
 	// 先读缓存
 	g.Mu.RLock()
-	cachedToken, ok := g.Cache[phone]
+	cached, ok := g.Cache[phone]
 	g.Mu.RUnlock()
 
-	if ok {
+	now := time.Now()
+	switch {
+	case !ok:
+	case cached.Expired(now, g.TicketTTL):
+		log.Printf("[Cache] phone=%s 缓存已超过有效期 %s（签发于 %s），重新登录", phone, g.TicketTTL, cached.IssuedAt.Format("2006-01-02 15:04:05"))
+	case !g.ProbeTickets || now.Sub(cached.ValidatedAt) < config.TicketProbeInterval:
 		log.Printf("[Cache] phone=%s 命中缓存", phone)
-		return cachedToken
+		return cached.Ticket
+	default:
+		err := validateTicketFunc(cached.Ticket)
+		if err == nil {
+			log.Printf("[Cache] phone=%s 命中缓存，校验通过", phone)
+			cached.ValidatedAt = now
+			if err := g.PutCache(phone, cached); err != nil {
+				log.Printf("[Warn] phone=%s 保存缓存失败: %v", phone, err)
+			}
+			return cached.Ticket
+		}
+		if !errors.Is(err, sign.ErrTicketInvalid) {
+			// 网络异常等无法判断有效性，继续使用缓存，避免开场前无谓地重新登录
+			log.Printf("[Warn] phone=%s ticket 校验失败，继续使用缓存: %v", phone, err)
+			return cached.Ticket
+		}
+		log.Printf("[Cache] phone=%s %v，重新登录", phone, err)
+	}
+	if ok {
+		if err := g.DeleteCache(phone); err != nil {
+			log.Printf("[Warn] phone=%s 删除失效缓存失败: %v", phone, err)
+		}
 	}
 
-	// 缓存无，则重新登录
-	log.Printf("[Login] phone=%s 开始重新登录", phone)
-	token, err := sign.UserLoginNormal(phone, password)
+	// 无人值守时无法输入验证码，不发送验证码短信，也不计入登录失败
+	if acc.AuthMethod() == sign.AuthSMS && !stdinIsTerminal() {
+		log.Printf("[Skip] phone=%s 需要短信验证码登录，但标准输入不是终端，请先执行 telecom login %s", phone, phone)
+		notifyFunc(acc.UID(), fmt.Sprintf("账号 %s 的 ticket 已失效，需要短信验证码登录，请执行 telecom login %s", phone, phone))
+		return ""
+	}
+
+	// 缓存无或已失效，则重新登录；连续失败的账号在退避期内或已暂停时不再尝试，避免被锁定
+	if reason, err := g.LoginBlocked(acc, time.Now()); err != nil {
+		log.Printf("[Warn] phone=%s 读取登录失败记录失败: %v", phone, err)
+	} else if reason != "" {
+		log.Printf("[Skip] phone=%s %s", phone, reason)
+		return ""
+	}
+	auth, err := newAuthenticator(acc)
+	if err != nil {
+		log.Printf("[Error] %v", err)
+		return ""
+	}
+	log.Printf("[Login] phone=%s 开始重新登录 (%s)", phone, auth.Method())
+	token, err := loginWithRetry(auth, phone)
 	if err != nil {
-		log.Printf("[Error] phone=%s 登录失败: %v", phone, err)
+		reportLoginError(acc, g, err)
+		return ""
+	}
+	if err := g.RecordLoginSuccess(phone); err != nil {
+		log.Printf("[Warn] phone=%s 清除登录失败记录失败: %v", phone, err)
+	}
+	if ok && auth.Method() == sign.AuthTicket && token == cached.Ticket {
+		log.Printf("[Error] phone=%s 配置的 ticket 已失效，请重新导入", phone)
 		return ""
 	}
 
-	// 写缓存
-	g.Mu.Lock()
-	g.Cache[phone] = token
-	// 保存到文件
-	g.SaveCache()
-	g.Mu.Unlock()
+	// 写缓存（PutCache 内部自行加锁）
+	issued := time.Now()
+	if err := g.PutCache(phone, store.Ticket{Ticket: token, IssuedAt: issued, ValidatedAt: issued}); err != nil {
+		log.Printf("[Warn] phone=%s 保存缓存失败: %v", phone, err)
+	}
 
 	return token
 }
 
-func executeTrading(g *config.GlobalVars, phone, token, uid string, client *http.Client, cfg *config.Config) {
-	log.Printf("[Trading] phone=%s", phone)
+// loginWithRetry 登录，服务暂不可用时退避重试（短信验证码登录除外），其他错误立即返回
+func loginWithRetry(auth sign.Authenticator, phone string) (string, error) {
+	retries := loginRetries
+	if auth.Method() == sign.AuthSMS {
+		// 每次登录都会重新下发验证码并等待输入，不自动重试
+		retries = 0
+	}
+	backoff := loginBackoff
+	for attempt := 0; ; attempt++ {
+		token, err := auth.Login(phone)
+		if err == nil || !sign.Retryable(err) || attempt >= retries {
+			return token, err
+		}
+		log.Printf("[Login] phone=%s %v，%s 后重试 (%d/%d)", phone, err, backoff, attempt+1, loginRetries)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
 
-	// 获取兑换商品列表并更新到 g.Jp
-	items := getTradeItems()
-	updateGlobalProducts(g, items)
+// reportLoginError 记录登录失败并持久化失败次数；凭证错误、账号锁定等需要人工处理的同时推送告警，
+// 因此暂停自动登录时另行推送
+func reportLoginError(acc config.Account, g *config.GlobalVars, err error) {
+	st, suspended, recErr := g.RecordLoginFailure(acc, err, time.Now())
+	if recErr != nil {
+		log.Printf("[Warn] phone=%s 保存登录失败记录失败: %v", acc.Phone, recErr)
+	}
+	switch {
+	case suspended:
+		log.Printf("[Alert] phone=%s 连续 %d 次登录失败，已暂停自动登录: %v", acc.Phone, st.CredentialFailures, err)
+		notifyFunc(acc.UID(), fmt.Sprintf("账号 %s 连续 %d 次登录失败（%v），已暂停自动登录以免被锁定。修改配置中的密码后自动恢复。",
+			acc.Phone, st.CredentialFailures, err))
+	case sign.NeedsAttention(err):
+		log.Printf("[Alert] phone=%s 登录失败，需要人工处理: %v", acc.Phone, err)
+		notifyFunc(acc.UID(), fmt.Sprintf("账号 %s 登录失败，需要人工处理: %v", acc.Phone, err))
+	default:
+		log.Printf("[Error] phone=%s 登录失败: %v", acc.Phone, err)
+	}
+}
+
+func executeTrading(g *config.GlobalVars, acc config.Account, session config.Session, sess *exchange.MallSession) {
+	phone, uid := acc.Phone, acc.UID()
+	log.Printf("[Trading] phone=%s", phone)
 
-	// 确定交易时间点
-	tradeHour := determineTradeHour(cfg)
+	if !acc.WantsSession(session.Name) {
+		log.Printf("[Skip] phone=%s 未参与场次 %s", phone, session.Name)
+		return
+	}
+	wanted, ok := session.ItemsFor(phone)
+	if !ok {
+		log.Printf("[Skip] phone=%s 在场次 %s 中已停用", phone, session.Name)
+		return
+	}
 
 	// 读全局 offset
 	g.Mu.RLock()
 	kswt := g.Kswt
 	g.Mu.RUnlock()
 
-	targetTime := float64(exchange.CalcT(tradeHour)) + kswt
+	targetTime := float64(exchange.CalcT(session)) + kswt
+	plan := session.Plan()
+	log.Printf("[Plan] phone=%s 每个商品尝试 %s", phone, plan)
 
-	// 读出 g.Jp[...] 需要读锁
+	// 读出 g.Jp[...] 需要读锁，按配置顺序筛选场次覆盖与账号配置中的商品，跳过本月已兑换的
+	products := make(map[string]string)
+	var order []string
 	g.Mu.RLock()
-	products := g.Jp[fmt.Sprintf("%d", tradeHour)]
+	cat := g.Catalog
+	for _, title := range wanted {
+		aid, ok := g.Jp[session.Name][title]
+		if !ok || !acc.WantsItem(title) || products[title] != "" {
+			continue
+		}
+		products[title] = aid
+		order = append(order, title)
+	}
 	g.Mu.RUnlock()
+	var pending []string
+	for _, title := range order {
+		if isAlreadyTraded(g, title, phone) {
+			log.Printf("[Skip] %s %s 已兑换", phone, title)
+			continue
+		}
+		pending = append(pending, title)
+	}
+
+	// 查询金豆余额，按优先顺序只保留买得起的商品；偏好模式下余额留给 exchange.Prefer 逐个扣减
+	maxItems := session.MaxItemsFor(phone)
+	budget := -1
+	if balance, err := sess.Balance(); err != nil {
+		log.Printf("[Warn] phone=%s 查询金豆余额失败，不按余额筛选: %v", phone, err)
+		g.SetBeansBefore(phone, config.BeansUnknown)
+	} else {
+		g.SetBeansBefore(phone, balance)
+		if maxItems > 0 {
+			budget = balance
+			log.Printf("[Beans] phone=%s 余额 %d，按偏好 %v 最多兑换 %d 个", phone, balance, pending, maxItems)
+		} else {
+			picked, skipped := cat.Affordable(session.Name, pending, balance)
+			for _, title := range skipped {
+				log.Printf("[Skip] phone=%s %s 金豆不足（余额 %d）", phone, title, balance)
+			}
+			log.Printf("[Beans] phone=%s 余额 %d，本场兑换 %v", phone, balance, picked)
+			pending = picked
+		}
+	}
+	for title := range products {
+		if !exchange.InStringArray(title, pending) {
+			delete(products, title)
+		}
+	}
 
 	// 先做预热
 	titles, aids := collectProductInfo(products)
 	phoneNum, _ := strconv.ParseInt(phone, 10, 32)
-	executeWarmupStages(g, int32(phoneNum), titles, aids, client, targetTime)
+	executeWarmupStages(g, int32(phoneNum), titles, aids, sess, targetTime)
+
+	// 判断是否超时
+	if len(pending) > 0 && isWaitingTooLong(targetTime) {
+		log.Println("[Timeout] 等待时间超过30分钟，退出")
+		return
+	}
 
 	// 正式交易
-	var tradeWg sync.WaitGroup
-	for title, aid := range products {
-		// 检查是否已交易
-		if isAlreadyTraded(g, title, int32(phoneNum)) {
-			log.Printf("[Skip] %d %s 已兑换", phoneNum, title)
-			continue
+	if maxItems > 0 {
+		// 偏好模式：按顺序逐个兑换，未兑到时退到下一个
+		prefs := make([]exchange.Preference, 0, len(pending))
+		for _, title := range pending {
+			item, _ := cat.Find(session.Name, title)
+			prefs = append(prefs, exchange.Preference{Title: title, ActivityID: products[title], Price: item.Price})
 		}
-		// 判断是否超时
-		if isWaitingTooLong(targetTime) {
-			log.Println("[Timeout] 等待时间超过30分钟，退出")
-			return
+		got := exchange.Prefer(g, session.Name, phone, prefs, maxItems, budget, targetTime, plan, uid, sess)
+		log.Printf("[Prefer] phone=%s 本场兑到 %v", phone, got)
+	} else {
+		var tradeWg sync.WaitGroup
+		for _, title := range pending {
+			log.Printf("[Trade] phone=%s item=%s", phone, title)
+			tradeWg.Add(1)
+			go func(t, a, u string) {
+				defer tradeWg.Done()
+				// 发起兑换
+				exchange.Dh(g, session.Name, phone, t, a, targetTime, plan, u, sess)
+			}(title, products[title], uid)
 		}
-		log.Printf("[Trade] phone=%d item=%s", phoneNum, title)
+		tradeWg.Wait()
+	}
 
-		tradeWg.Add(1)
-		go func(t, a, u string) {
-			defer tradeWg.Done()
-			// 发起兑换
-			exchange.Dh(g, phone, t, a, targetTime, u, client)
-		}(title, aid, uid)
+	if len(pending) == 0 {
+		return
+	}
+	if balance, err := sess.Balance(); err != nil {
+		log.Printf("[Warn] phone=%s 兑换后查询金豆余额失败: %v", phone, err)
+		g.SetBeansAfter(phone, config.BeansUnknown)
+	} else {
+		g.SetBeansAfter(phone, balance)
 	}
-	tradeWg.Wait()
 }
 
-func getTradeItems() []struct {
-	Title string
-	ID    string
-} {
-	return []struct {
-		Title string
-		ID    string
-	}{
-		{"0.5元话费", "aid_0.5"},
-		{"5元话费", "aid_5"},
-		{"6元话费", "aid_6"},
-		{"1元话费", "aid_1"},
-		{"10元话费", "aid_10"},
-		{"3元话费", "aid_3"},
+// loadCatalog 获取场次的商品目录：当天缓存 → 商城 → 目录文件
+func loadCatalog(cfg *config.Config, session config.Session) (*catalog.Catalog, error) {
+	h, m, sec, err := session.Clock()
+	if err != nil {
+		return nil, err
 	}
+	return catalog.Load(catalog.Options{
+		CacheFile:    cfg.Paths.Catalog(),
+		FallbackFile: cfg.CatalogFile,
+		Sessions:     map[string]string{session.Name: fmt.Sprintf("%02d:%02d:%02d", h, m, sec)},
+	}, time.Now())
 }
 
-func updateGlobalProducts(g *config.GlobalVars, items []struct{ Title, ID string }) {
-	// 先读出morningEx/afternoonEx
+// updateGlobalProducts 按目录将各场次配置的商品标题映射到 activityId，写入 g.Jp
+func updateGlobalProducts(g *config.GlobalVars, cat *catalog.Catalog) {
+	// 先读出场次列表
 	g.Mu.RLock()
-	morningEx := g.MorningExchanges
-	afternoonEx := g.AfternoonExchanges
+	sessions := g.Sessions
 	g.Mu.RUnlock()
 
 	// 写 g.Jp时需加写锁
 	g.Mu.Lock()
 	defer g.Mu.Unlock()
-	for _, item := range items {
-		if exchange.InStringArray(item.Title, morningEx) {
-			g.Jp["10"][item.Title] = item.ID
-		} else if exchange.InStringArray(item.Title, afternoonEx) {
-			g.Jp["14"][item.Title] = item.ID
+	g.Catalog = cat
+	for _, s := range sessions {
+		items := cat.Items(s.Name)
+		if items == nil {
+			continue
+		}
+		for _, title := range s.AllTitles() {
+			item, ok := cat.Find(s.Name, title)
+			if !ok {
+				log.Printf("[Catalog] 场次 %s 的商品列表中没有 %s", s.Name, title)
+				continue
+			}
+			if item.Status == catalog.StockSoldOut {
+				log.Printf("[Catalog] 场次 %s 的 %s 已无库存", s.Name, title)
+			}
+			g.Jp[s.Name][title] = item.ActivityID
 		}
 	}
 }
 
-func determineTradeHour(cfg *config.Config) int {
+// determineSession 选择本次参与的场次：
+// 1. 指定场次名 (--session / TELECOM_SESSION / strategy.session)
+// 2. 指定交易时段 (--trade-hour / CTIME / strategy.tradeHour)，匹配开场小时
+// 3. 按当前时间选择最近一个仍可参与的场次，全部已结束时取最后开场的场次
+func determineSession(cfg *config.Config, now time.Time) (config.Session, error) {
+	if len(cfg.Sessions) == 0 {
+		return config.Session{}, fmt.Errorf("未配置任何场次")
+	}
+	if cfg.Session != "" {
+		s, ok := config.FindSession(cfg.Sessions, cfg.Session)
+		if !ok {
+			return config.Session{}, fmt.Errorf("场次 %s 不存在", cfg.Session)
+		}
+		return s, nil
+	}
 	if cfg.H != nil {
-		return *cfg.H
+		for _, s := range cfg.Sessions {
+			if h, _, _, err := s.Clock(); err == nil && h == *cfg.H {
+				return s, nil
+			}
+		}
+		return config.Session{}, fmt.Errorf("没有在 %d 点开场的场次", *cfg.H)
+	}
+
+	var best, latest config.Session
+	var bestAt, latestAt time.Time
+	for _, s := range cfg.Sessions {
+		if _, _, _, err := s.Clock(); err != nil {
+			return config.Session{}, fmt.Errorf("场次 %s: %w", s.Name, err)
+		}
+		at, ok := s.Next(now)
+		if ok && (bestAt.IsZero() || at.Before(bestAt)) {
+			best, bestAt = s, at
+		}
+		if !ok && at.After(latestAt) {
+			latest, latestAt = s, at
+		}
 	}
-	nowH := time.Now().Hour()
-	if nowH < 11 {
-		return 10
+	if !bestAt.IsZero() {
+		return best, nil
 	}
-	return 14
+	return latest, nil
 }
 
 func collectProductInfo(products map[string]string) ([]string, []string) {
@@ -231,19 +516,23 @@ func collectProductInfo(products map[string]string) ([]string, []string) {
 	return titles, aids
 }
 
-func executeWarmupStages(g *config.GlobalVars, phone int32, titles, aids []string, client *http.Client, targetTime float64) {
+func executeWarmupStages(g *config.GlobalVars, phone int32, titles, aids []string, sess *exchange.MallSession, targetTime float64) {
 	var wg sync.WaitGroup
 
 	baseTime := time.Unix(int64(targetTime), 0)
 	emptyRequestTime := baseTime.Add(-3 * time.Second)
 	realRequestTime := baseTime.Add(-1 * time.Second)
+	if rec := sess.Recorder(); rec != nil {
+		rec.Plan(sess.Phone, "", "", exchange.StageEmpty, emptyRequestTime)
+		rec.Plan(sess.Phone, "", "", exchange.StageWarmup, realRequestTime)
+	}
 
 	if time.Now().Before(emptyRequestTime) {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
 			scheduleStage(emptyRequestTime, "抢发阶段", func() {
-				exchange.DoHighFreqRequests(emptyRequestTime, fmt.Sprint(phone), client, nil)
+				exchange.DoHighFreqRequests(emptyRequestTime, fmt.Sprint(phone), sess, nil)
 			})
 		}()
 	} else {
@@ -255,7 +544,7 @@ func executeWarmupStages(g *config.GlobalVars, phone int32, titles, aids []strin
 		go func() {
 			defer wg.Done()
 			scheduleStage(realRequestTime, "预热阶段", func() {
-				exchange.DoHighFreqRealRequests(realRequestTime, fmt.Sprint(phone), titles, aids, client, nil)
+				exchange.DoHighFreqRealRequests(realRequestTime, fmt.Sprint(phone), titles, aids, sess, nil)
 			})
 		}()
 	} else {
@@ -275,7 +564,7 @@ func scheduleStage(scheduledTime time.Time, stageName string, task func()) {
 	task()
 }
 
-func isAlreadyTraded(g *config.GlobalVars, title string, phone int32) bool {
+func isAlreadyTraded(g *config.GlobalVars, title string, phone string) bool {
 	// 加读写锁访问 Dhjl
 	g.Mu.RLock()
 	yf := g.Yf
@@ -292,9 +581,8 @@ func isAlreadyTraded(g *config.GlobalVars, title string, phone int32) bool {
 		return false
 	}
 
-	phoneStr := fmt.Sprint(phone)
 	for _, p := range phones {
-		if p == phoneStr {
+		if p == phone {
 			return true
 		}
 	}
@@ -316,35 +604,42 @@ func waitUntilTargetTime(targetTime float64) {
 func handleExchangeLog(g *config.GlobalVars) {
 	nowMonth := time.Now().Format("200601")
 
-	g.Mu.RLock()
-	oldLog, ok := g.Dhjl[nowMonth]
-	g.Mu.RUnlock()
+	// 以账本为准按手机号汇总当月成功记录
+	records, err := g.Store.Records()
+	if err != nil {
+		log.Printf("[Warn] 读取兑换账本失败: %v", err)
+		return
+	}
+	dhjl2 := ledger.PhoneView(ledger.Filter(records, nowMonth, "", ""))
 
-	dhjl2 := make(map[string]map[string][]string)
+	data, _ := json.MarshalIndent(dhjl2, "", "  ")
+	if err := util.WriteFileAtomic(g.Paths.ExchangeLog2(), data, 0644); err != nil {
+		log.Printf("[Warn] 保存 %s 失败: %v", g.Paths.ExchangeLog2(), err)
+	}
+}
 
-	if ok {
-		// 为安全考虑，将其复制到临时结构再处理
-		copyOldLog := make(map[string][]string)
-		g.Mu.RLock()
-		for fee, phones := range oldLog {
-			// 做浅拷贝
-			copyOldLog[fee] = append([]string(nil), phones...)
+// saveRun 写入运行历史，失败只记日志
+func saveRun(g *config.GlobalVars, run store.Run) {
+	if err := g.Store.SaveRun(run); err != nil {
+		log.Printf("[Warn] 保存运行历史失败: %v", err)
+	}
+}
+
+// finishRun 统计本次运行期间的兑换尝试并标记结束
+func finishRun(g *config.GlobalVars, run store.Run) {
+	records, err := g.Store.Records()
+	if err != nil {
+		run.Error = err.Error()
+	}
+	for _, r := range records {
+		if r.Session != run.Session || r.Time.Before(run.StartedAt) {
+			continue
 		}
-		g.Mu.RUnlock()
-
-		for fee, phones := range copyOldLog {
-			for _, phone := range phones {
-				if phone == "" {
-					continue
-				}
-				if _, ok2 := dhjl2[phone]; !ok2 {
-					dhjl2[phone] = make(map[string][]string)
-				}
-				dhjl2[phone][nowMonth] = append(dhjl2[phone][nowMonth], fee)
-			}
+		run.Attempts++
+		if r.Outcome == ledger.OutcomeSuccess {
+			run.Successes++
 		}
 	}
-
-	data, _ := json.MarshalIndent(dhjl2, "", "  ")
-	_ = os.WriteFile(config.ExchangeLogFile2, data, 0644)
+	run.FinishedAt = time.Now()
+	saveRun(g, run)
 }
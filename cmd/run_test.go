@@ -0,0 +1,239 @@
+package cmd
+
+import (
+	"HighFrequencyTrading/config"
+	"HighFrequencyTrading/sign"
+	"HighFrequencyTrading/store"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeAuth 测试用的登录器
+type fakeAuth func() (string, error)
+
+func (f fakeAuth) Method() string                     { return "fake" }
+func (f fakeAuth) Login(phone string) (string, error) { return f() }
+
+func TestGetTokenCache(t *testing.T) {
+	const phone = "13800138000"
+	now := time.Now()
+
+	tests := []struct {
+		name       string
+		cached     *store.Ticket
+		probeErr   error
+		wantProbe  bool
+		wantLogin  bool
+		wantTicket string
+	}{
+		{name: "无缓存", wantLogin: true, wantTicket: "new"},
+		{name: "刚校验过", cached: &store.Ticket{Ticket: "old", IssuedAt: now.Add(-time.Hour), ValidatedAt: now.Add(-time.Minute)}, wantTicket: "old"},
+		{name: "超过有效期", cached: &store.Ticket{Ticket: "old", IssuedAt: now.Add(-13 * time.Hour), ValidatedAt: now}, wantLogin: true, wantTicket: "new"},
+		{name: "校验通过", cached: &store.Ticket{Ticket: "old", IssuedAt: now.Add(-time.Hour)}, wantProbe: true, wantTicket: "old"},
+		{name: "校验失效", cached: &store.Ticket{Ticket: "old"}, probeErr: fmt.Errorf("%w: code=1", sign.ErrTicketInvalid), wantProbe: true, wantLogin: true, wantTicket: "new"},
+		{name: "校验网络错误", cached: &store.Ticket{Ticket: "old"}, probeErr: errors.New("timeout"), wantProbe: true, wantTicket: "old"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Paths:        config.Paths{Profile: config.DefaultProfile, Dir: t.TempDir()},
+				TicketTTL:    config.DefaultTicketTTL,
+				ProbeTickets: true,
+			}
+			g, err := config.InitGlobalVars(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer g.Close()
+			if tt.cached != nil {
+				g.PutCache(phone, *tt.cached)
+			}
+
+			oldAuth, oldValidate := newAuthenticator, validateTicketFunc
+			defer func() { newAuthenticator, validateTicketFunc = oldAuth, oldValidate }()
+
+			var probed, loggedIn bool
+			validateTicketFunc = func(string) error { probed = true; return tt.probeErr }
+			newAuthenticator = func(config.Account) (sign.Authenticator, error) {
+				return fakeAuth(func() (string, error) { loggedIn = true; return "new", nil }), nil
+			}
+
+			if got := getToken(config.Account{Phone: phone, Password: "123456"}, g); got != tt.wantTicket {
+				t.Errorf("ticket = %q, 期望 %q", got, tt.wantTicket)
+			}
+			if probed != tt.wantProbe || loggedIn != tt.wantLogin {
+				t.Errorf("校验=%v 登录=%v, 期望 校验=%v 登录=%v", probed, loggedIn, tt.wantProbe, tt.wantLogin)
+			}
+
+			// 存储中的缓存应与返回值一致，重新登录或校验通过后验证时间被刷新
+			c, err := g.Store.Cache()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c[phone].Ticket != tt.wantTicket {
+				t.Errorf("存储中的 ticket = %q, 期望 %q", c[phone].Ticket, tt.wantTicket)
+			}
+			if (tt.wantLogin || (tt.wantProbe && tt.probeErr == nil)) && time.Since(c[phone].ValidatedAt) > time.Minute {
+				t.Errorf("验证时间未刷新: %v", c[phone].ValidatedAt)
+			}
+		})
+	}
+}
+
+func TestGetTokenLoginErrors(t *testing.T) {
+	const phone = "13800138000"
+	unavailable := &sign.LoginError{Kind: sign.ErrServiceUnavailable, Status: 503}
+	tests := []struct {
+		name       string
+		errs       []error // 依次作为每次登录的结果，用完后登录成功
+		wantCalls  int
+		wantTicket string
+		wantAlert  bool
+	}{
+		{name: "暂不可用后恢复", errs: []error{unavailable, unavailable}, wantCalls: 3, wantTicket: "new"},
+		{name: "重试用尽", errs: []error{unavailable, unavailable, unavailable}, wantCalls: 3},
+		{name: "密码错误不重试", errs: []error{&sign.LoginError{Kind: sign.ErrWrongPassword, Code: "8105"}}, wantCalls: 1, wantAlert: true},
+		{name: "账号锁定不重试", errs: []error{&sign.LoginError{Kind: sign.ErrAccountLocked, Code: "8107"}}, wantCalls: 1, wantAlert: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{Paths: config.Paths{Profile: config.DefaultProfile, Dir: t.TempDir()}}
+			g, err := config.InitGlobalVars(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer g.Close()
+
+			oldAuth, oldNotify, oldBackoff := newAuthenticator, notifyFunc, loginBackoff
+			defer func() { newAuthenticator, notifyFunc, loginBackoff = oldAuth, oldNotify, oldBackoff }()
+			loginBackoff = time.Millisecond
+
+			calls := 0
+			newAuthenticator = func(config.Account) (sign.Authenticator, error) {
+				return fakeAuth(func() (string, error) {
+					calls++
+					if calls <= len(tt.errs) {
+						return "", tt.errs[calls-1]
+					}
+					return "new", nil
+				}), nil
+			}
+			var alerted bool
+			notifyFunc = func(uid, content string) { alerted = true }
+
+			if got := getToken(config.Account{Phone: phone, Password: "123456"}, g); got != tt.wantTicket {
+				t.Errorf("ticket = %q, 期望 %q", got, tt.wantTicket)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("登录 %d 次, 期望 %d", calls, tt.wantCalls)
+			}
+			if alerted != tt.wantAlert {
+				t.Errorf("告警=%v, 期望 %v", alerted, tt.wantAlert)
+			}
+		})
+	}
+}
+
+// fakeSMSAuth 测试用的短信验证码登录器
+type fakeSMSAuth func() (string, error)
+
+func (f fakeSMSAuth) Method() string                     { return sign.AuthSMS }
+func (f fakeSMSAuth) Login(phone string) (string, error) { return f() }
+
+func TestLoginWithRetryDoesNotResendSMS(t *testing.T) {
+	oldBackoff := loginBackoff
+	defer func() { loginBackoff = oldBackoff }()
+	loginBackoff = time.Millisecond
+
+	unavailable := &sign.LoginError{Kind: sign.ErrServiceUnavailable}
+	calls := 0
+	if _, err := loginWithRetry(fakeAuth(func() (string, error) { calls++; return "", unavailable }), "13800138000"); err == nil || calls != loginRetries+1 {
+		t.Fatalf("密码登录应重试 %d 次，实际登录 %d 次: %v", loginRetries, calls, err)
+	}
+	calls = 0
+	if _, err := loginWithRetry(fakeSMSAuth(func() (string, error) { calls++; return "", unavailable }), "13800138000"); err == nil || calls != 1 {
+		t.Fatalf("短信验证码登录不应重试（每次都会重新下发验证码），实际登录 %d 次: %v", calls, err)
+	}
+}
+
+func TestGetTokenBacksOffAfterFailure(t *testing.T) {
+	cfg := &config.Config{Paths: config.Paths{Profile: config.DefaultProfile, Dir: t.TempDir()}, MaxLoginFailures: 2}
+	g, err := config.InitGlobalVars(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+
+	oldAuth, oldNotify := newAuthenticator, notifyFunc
+	defer func() { newAuthenticator, notifyFunc = oldAuth, oldNotify }()
+	calls := 0
+	newAuthenticator = func(config.Account) (sign.Authenticator, error) {
+		return fakeAuth(func() (string, error) {
+			calls++
+			return "", &sign.LoginError{Kind: sign.ErrWrongPassword, Code: "8105"}
+		}), nil
+	}
+	var alerts []string
+	notifyFunc = func(uid, content string) { alerts = append(alerts, content) }
+
+	acc := config.Account{Phone: "13800138000", Password: "123456"}
+	getToken(acc, g)
+	getToken(acc, g)
+	if calls != 1 {
+		t.Fatalf("退避期内不应再次登录，实际登录 %d 次", calls)
+	}
+
+	// 退避期满后再次失败即达到上限，暂停并推送
+	st, _ := g.Store.LoginStates()
+	s := st[acc.Phone]
+	s.NextAttempt = time.Now().Add(-time.Second)
+	g.Store.PutLoginState(acc.Phone, s)
+	getToken(acc, g)
+	if calls != 2 || len(alerts) != 2 {
+		t.Fatalf("登录 %d 次，告警 %d 条", calls, len(alerts))
+	}
+	if st, _ := g.Store.LoginStates(); !st[acc.Phone].Suspended() {
+		t.Errorf("应已暂停: %+v", st[acc.Phone])
+	}
+}
+
+func TestGetTokenSkipsSMSWithoutTerminal(t *testing.T) {
+	cfg := &config.Config{Paths: config.Paths{Profile: config.DefaultProfile, Dir: t.TempDir()}}
+	g, err := config.InitGlobalVars(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+
+	oldAuth, oldNotify, oldTerminal := newAuthenticator, notifyFunc, stdinIsTerminal
+	defer func() { newAuthenticator, notifyFunc, stdinIsTerminal = oldAuth, oldNotify, oldTerminal }()
+	stdinIsTerminal = func() bool { return false }
+	calls := 0
+	newAuthenticator = func(config.Account) (sign.Authenticator, error) {
+		return fakeAuth(func() (string, error) { calls++; return "new", nil }), nil
+	}
+	var alerts []string
+	notifyFunc = func(uid, content string) { alerts = append(alerts, content) }
+
+	acc := config.Account{Phone: "13800138000", Auth: sign.AuthSMS}
+	if got := getToken(acc, g); got != "" || calls != 0 {
+		t.Fatalf("无终端时不应发起短信登录: ticket=%q 登录 %d 次", got, calls)
+	}
+	if len(alerts) != 1 || !strings.Contains(alerts[0], "telecom login 13800138000") {
+		t.Errorf("应提示执行 telecom login: %v", alerts)
+	}
+	if st, _ := g.Store.LoginStates(); st[acc.Phone].CredentialFailures != 0 || !st[acc.Phone].NextAttempt.IsZero() {
+		t.Errorf("不应记录登录失败: %+v", st[acc.Phone])
+	}
+
+	// 交互终端中照常登录
+	stdinIsTerminal = func() bool { return true }
+	if got := getToken(acc, g); got != "new" || calls != 1 {
+		t.Errorf("终端中应照常登录: ticket=%q 登录 %d 次", got, calls)
+	}
+}
@@ -0,0 +1,280 @@
+package cmd
+
+import (
+	"HighFrequencyTrading/config"
+	"HighFrequencyTrading/secret"
+	"HighFrequencyTrading/util"
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"io"
+	"os"
+	"strings"
+)
+
+var (
+	secretsCmd = &cobra.Command{
+		Use:   "secrets",
+		Short: "加密存储密码与缓存 ticket",
+		Long: `使用口令派生的 AES-GCM 密钥加密账号密码与 ticket 缓存。
+口令来源优先级: 环境变量 ` + secret.EnvKey + ` → 密钥文件 (` + secret.EnvKeyFile + `，默认为 profile 目录下的 ` + config.KeyFile + `)`,
+		SilenceUsage: true,
+	}
+
+	secretsInitCmd = &cobra.Command{
+		Use:   "init",
+		Short: "生成密钥文件并加密已有缓存与保险库",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			paths, err := profilePaths()
+			if err != nil {
+				return err
+			}
+			lock, err := lockSecrets(paths)
+			if err != nil {
+				return err
+			}
+			defer lock.Unlock()
+			box, err := secret.Load(paths.KeyFile())
+			if err != nil {
+				return err
+			}
+			if box == nil {
+				key, err := secret.GenerateKey()
+				if err != nil {
+					return err
+				}
+				path := secret.KeyFilePath(paths.KeyFile())
+				if err := os.WriteFile(path, []byte(key+"\n"), 0600); err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "已生成密钥文件 %s，请妥善备份\n", path)
+				box = secret.NewBox(key)
+			} else {
+				fmt.Fprintln(cmd.OutOrStdout(), "已存在加密口令，跳过生成")
+			}
+			return resealAll(cmd, paths, box, box)
+		},
+	}
+
+	secretsRotateCmd = &cobra.Command{
+		Use:   "rotate",
+		Short: "生成新密钥并重新加密所有数据",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if os.Getenv(secret.EnvKey) != "" {
+				return errors.New("口令来自环境变量 " + secret.EnvKey + "，请改用密钥文件后再轮换")
+			}
+			paths, err := profilePaths()
+			if err != nil {
+				return err
+			}
+			lock, err := lockSecrets(paths)
+			if err != nil {
+				return err
+			}
+			defer lock.Unlock()
+			oldBox, err := secret.Load(paths.KeyFile())
+			if err != nil {
+				return err
+			}
+			if oldBox == nil {
+				return errors.New("尚未初始化，请先执行 telecom secrets init")
+			}
+			// 配置文件中的 enc:v1: 值不在轮换范围内，轮换后将无法解密，必须先处理
+			cfg, err := config.NewConfig(rootOptions())
+			if err != nil {
+				return fmt.Errorf("读取配置失败，无法确认其中是否有加密值: %w", err)
+			}
+			if sealed := cfg.SealedValues(); len(sealed) > 0 {
+				out := cmd.ErrOrStderr()
+				fmt.Fprintln(out, "以下值使用当前密钥加密，轮换后将无法解密:")
+				for _, loc := range sealed {
+					fmt.Fprintf(out, "  %s\n", loc)
+				}
+				fmt.Fprintln(out, "请先将密码改存到保险库 (telecom secrets set)，或暂时改为明文；轮换后再用 telecom secrets encrypt 重新生成")
+				return fmt.Errorf("配置中有 %d 个 enc:v1: 值，已取消轮换", len(sealed))
+			}
+			key, err := secret.GenerateKey()
+			if err != nil {
+				return err
+			}
+
+			// 先写临时密钥文件，数据全部重新加密后再替换，避免中途失败丢失口令
+			path := secret.KeyFilePath(paths.KeyFile())
+			tmp := path + ".new"
+			if err := os.WriteFile(tmp, []byte(key+"\n"), 0600); err != nil {
+				return err
+			}
+			if err := resealAll(cmd, paths, oldBox, secret.NewBox(key)); err != nil {
+				return fmt.Errorf("%w (新密钥保留在 %s)", err, tmp)
+			}
+			if err := os.Rename(tmp, path); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "密钥已轮换: %s\n", path)
+			return nil
+		},
+	}
+
+	secretsReencryptCmd = &cobra.Command{
+		Use:   "reencrypt",
+		Short: "用当前密钥重新加密缓存与保险库（含明文条目）",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			paths, err := profilePaths()
+			if err != nil {
+				return err
+			}
+			lock, err := lockSecrets(paths)
+			if err != nil {
+				return err
+			}
+			defer lock.Unlock()
+			box, err := requireBox(paths)
+			if err != nil {
+				return err
+			}
+			return resealAll(cmd, paths, box, box)
+		},
+	}
+
+	secretsSetCmd = &cobra.Command{
+		Use:     "set <phone>",
+		Short:   "从标准输入读取密码并加密保存到保险库",
+		Example: `echo 'p#ss&word' | telecom secrets set 13800138000`,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			paths, err := profilePaths()
+			if err != nil {
+				return err
+			}
+			password, err := readSecretLine(cmd.InOrStdin())
+			if err != nil {
+				return err
+			}
+			// 与运行中的实例一样使用当前口令，只需与重新加密互斥
+			lock, err := config.LockSecrets(paths, true)
+			if err != nil {
+				return err
+			}
+			defer lock.Unlock()
+			box, err := requireBox(paths)
+			if err != nil {
+				return err
+			}
+			vault, err := secret.LoadVault(paths.Vault())
+			if err != nil {
+				return err
+			}
+			if err := vault.SetPassword(box, args[0], password); err != nil {
+				return err
+			}
+			if err := vault.Save(); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "已保存 %s 的密码，配置中该账号的 password 可留空\n", args[0])
+			return nil
+		},
+	}
+
+	secretsEncryptCmd = &cobra.Command{
+		Use:     "encrypt",
+		Short:   "加密标准输入中的一行，输出可直接写入配置的 enc:v1: 值",
+		Example: `echo 'p#ss&word' | telecom secrets encrypt`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			paths, err := profilePaths()
+			if err != nil {
+				return err
+			}
+			box, err := requireBox(paths)
+			if err != nil {
+				return err
+			}
+			plain, err := readSecretLine(cmd.InOrStdin())
+			if err != nil {
+				return err
+			}
+			sealed, err := box.Seal(plain)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), sealed)
+			return nil
+		},
+	}
+)
+
+// requireBox 加载口令，未配置时报错
+func requireBox(paths config.Paths) (*secret.Box, error) {
+	box, err := secret.Load(paths.KeyFile())
+	if err != nil {
+		return nil, err
+	}
+	if box == nil {
+		return nil, secret.ErrNoKey
+	}
+	return box, nil
+}
+
+// lockSecrets 取得口令的排他锁，有实例正在使用当前口令时报错
+func lockSecrets(paths config.Paths) (*util.FileLock, error) {
+	lock, err := config.LockSecrets(paths, false)
+	if errors.Is(err, util.ErrLocked) {
+		return nil, errors.New("有正在运行的兑换或登录使用当前口令，请在其结束后重试")
+	}
+	return lock, err
+}
+
+// resealAll 用 newBox 重新加密保险库与 ticket 缓存，调用方需持有 lockSecrets 的锁。
+// 两者先在内存中重新加密，保险库写入临时文件后再逐个写回缓存，最后替换保险库文件：
+// 中途失败或进程退出时保险库仍为旧口令加密，只有部分缓存 ticket 无法解密，下次运行时重新登录即可
+func resealAll(cmd *cobra.Command, paths config.Paths, oldBox, newBox *secret.Box) error {
+	vault, err := secret.LoadVault(paths.Vault())
+	if err != nil {
+		return err
+	}
+	if err := vault.Reseal(oldBox, newBox); err != nil {
+		return err
+	}
+	st, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+	cache, err := config.ResealCache(st, oldBox, newBox)
+	if err != nil {
+		return err
+	}
+
+	pending, err := vault.Stage()
+	if err != nil {
+		return err
+	}
+	defer pending.Discard()
+	for phone, t := range cache {
+		if err := st.PutCache(phone, t); err != nil {
+			return err
+		}
+	}
+	if err := pending.Commit(); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "已加密 %d 个密码、%d 个缓存 ticket\n", len(vault.Passwords), len(cache))
+	return nil
+}
+
+// readSecretLine 从输入读取一行并去掉换行符
+func readSecretLine(r io.Reader) (string, error) {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return "", errors.New("未读取到内容")
+	}
+	return line, nil
+}
+
+func init() {
+	secretsCmd.AddCommand(secretsInitCmd, secretsRotateCmd, secretsReencryptCmd, secretsSetCmd, secretsEncryptCmd)
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"HighFrequencyTrading/config"
+	"HighFrequencyTrading/store"
+	"fmt"
+	"github.com/spf13/cobra"
+	"text/tabwriter"
+	"time"
+)
+
+var (
+	storeCmd = &cobra.Command{
+		Use:   "store",
+		Short: "管理兑换账本、ticket 缓存与运行历史的存储",
+		Long: `存储后端由配置文件的 storage 字段或环境变量 TELECOM_STORAGE 指定：
+  ` + store.BackendJSON + `    数据目录下的 JSON 文件（默认）
+  ` + store.BackendSQLite + `  数据目录下的 ` + store.SQLiteFile,
+		SilenceUsage: true,
+	}
+
+	storeMigrateForce bool
+	storeMigrateFrom  string
+
+	storeMigrateCmd = &cobra.Command{
+		Use:   "migrate",
+		Short: "将旧版兑换日志、缓存与 JSON 账本导入当前存储",
+		Long: `导入数据目录中的 ` + store.LedgerFile + `，以及数据目录中的 ` + config.ExchangeLogFile + `、` + config.CacheFile + `；
+默认 profile 还会查找当前目录，其它 profile 需用 --from 指定旧版本的工作目录。
+每个存储只自动导入一次（首次运行时），已存在的记录不会重复导入；--force 或 --from 可再次执行。`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.NewConfig(rootOptions())
+			if err != nil {
+				return err
+			}
+			st, err := store.Open(cfg.Storage, cfg.Paths.Dir)
+			if err != nil {
+				return err
+			}
+			defer st.Close()
+			dirs, force := cfg.Paths.LegacyDirs(), storeMigrateForce
+			if storeMigrateFrom != "" {
+				dirs, force = []string{storeMigrateFrom}, true
+			}
+			res, err := store.ImportLegacy(st, cfg.Paths.Dir, force, dirs...)
+			if err != nil {
+				return err
+			}
+			if res.Skipped {
+				fmt.Fprintln(cmd.OutOrStdout(), "已导入过，跳过（使用 --force 重新检查）")
+				return nil
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "已导入 %d 条兑换记录、%d 个缓存 ticket 到 %s 存储\n", res.Records, res.Tickets, cfg.Storage)
+			return nil
+		},
+	}
+
+	storeRunsCmd = &cobra.Command{
+		Use:   "runs",
+		Short: "列出运行历史",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			st, err := openStore()
+			if err != nil {
+				return err
+			}
+			defer st.Close()
+			runs, err := st.Runs()
+			if err != nil {
+				return err
+			}
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tSESSION\tSTARTED\tDURATION\tACCOUNTS\tATTEMPTS\tSUCCESSES\tERROR")
+			for _, r := range runs {
+				duration := "(未结束)"
+				if !r.FinishedAt.IsZero() {
+					duration = r.FinishedAt.Sub(r.StartedAt).Round(time.Second).String()
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%d\t%d\t%s\n", r.ID, r.Session,
+					r.StartedAt.Format("2006-01-02 15:04:05"), duration, r.Accounts, r.Attempts, r.Successes, r.Error)
+			}
+			return w.Flush()
+		},
+	}
+)
+
+// openStore 按当前配置打开存储（首次打开时自动导入旧数据）
+func openStore() (store.Store, error) {
+	cfg, err := config.NewConfig(rootOptions())
+	if err != nil {
+		return nil, err
+	}
+	return cfg.OpenStore()
+}
+
+func init() {
+	storeMigrateCmd.Flags().BoolVar(&storeMigrateForce, "force", false, "忽略已导入标记重新导入")
+	storeMigrateCmd.Flags().StringVar(&storeMigrateFrom, "from", "", "从该目录导入旧版兑换日志与缓存（替代当前目录）")
+	storeCmd.AddCommand(storeMigrateCmd, storeRunsCmd)
+}
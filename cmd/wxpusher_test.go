@@ -28,6 +28,10 @@ func TestWxPusherCommand(t *testing.T) {
 	// 测试完成后切换回原目录
 	defer os.Chdir(currentDir)
 
+	// Execute 总是从根命令开始解析参数，需显式指定子命令
+	rootCmd.SetArgs([]string{"wxpusher"})
+	defer rootCmd.SetArgs(nil)
+
 	tests := []struct {
 		name    string
 		yaml    string
@@ -96,6 +100,9 @@ func TestWxPusherCommandNoFile(t *testing.T) {
 	}
 	defer os.Chdir(currentDir)
 
+	rootCmd.SetArgs([]string{"wxpusher"})
+	defer rootCmd.SetArgs(nil)
+
 	if err := wxpusherCmd.Execute(); err == nil {
 		t.Error("期望文件不存在时返回错误，但没有")
 	}
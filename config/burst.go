@@ -0,0 +1,98 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	DefaultBurstInterval = 200 * time.Millisecond // 未配置时相邻两次尝试的间隔
+	MaxBurstAttempts     = 20                     // 单个商品的最多尝试次数，避免触发风控
+	MaxBurstLead         = 3 * time.Second        // 首次尝试最多提前于开场的时长
+)
+
+// Burst : 开场时每个商品的尝试计划，场次中的 burst 逐项覆盖 strategy.burst
+type Burst struct {
+	Attempts int    `yaml:"attempts,omitempty" toml:"attempts" json:"attempts,omitempty"` // 尝试次数，默认 1
+	Interval string `yaml:"interval,omitempty" toml:"interval" json:"interval,omitempty"` // 相邻两次尝试的间隔，如 "150ms"
+	Lead     string `yaml:"lead,omitempty" toml:"lead" json:"lead,omitempty"`             // 首次尝试提前于开场的时长，如 "100ms"
+}
+
+// AttemptPlan : 解析后的尝试计划
+type AttemptPlan struct {
+	Attempts int
+	Interval time.Duration
+	Lead     time.Duration
+}
+
+// merge : 以 o 中已设置的字段覆盖 b
+func (b *Burst) merge(o *Burst) *Burst {
+	if b == nil {
+		return o
+	}
+	if o == nil {
+		return b
+	}
+	res := *b
+	if o.Attempts != 0 {
+		res.Attempts = o.Attempts
+	}
+	if o.Interval != "" {
+		res.Interval = o.Interval
+	}
+	if o.Lead != "" {
+		res.Lead = o.Lead
+	}
+	return &res
+}
+
+// Plan : 解析尝试计划，未设置的字段取默认值
+func (b *Burst) Plan() (AttemptPlan, error) {
+	p := AttemptPlan{Attempts: 1, Interval: DefaultBurstInterval}
+	if b == nil {
+		return p, nil
+	}
+	if b.Attempts != 0 {
+		if b.Attempts < 1 || b.Attempts > MaxBurstAttempts {
+			return p, fmt.Errorf("attempts 应在 1 到 %d 之间: %d", MaxBurstAttempts, b.Attempts)
+		}
+		p.Attempts = b.Attempts
+	}
+	if b.Interval != "" {
+		d, err := time.ParseDuration(b.Interval)
+		if err != nil || d <= 0 {
+			return p, fmt.Errorf("interval %q 应为正的时长，如 150ms", b.Interval)
+		}
+		p.Interval = d
+	}
+	if b.Lead != "" {
+		d, err := time.ParseDuration(b.Lead)
+		if err != nil || d < 0 || d > MaxBurstLead {
+			return p, fmt.Errorf("lead %q 应为 0 到 %s 之间的时长", b.Lead, MaxBurstLead)
+		}
+		p.Lead = d
+	}
+	return p, nil
+}
+
+// Times : 以开场时间 t0 计算每次尝试的发送时间
+func (p AttemptPlan) Times(t0 time.Time) []time.Time {
+	res := make([]time.Time, p.Attempts)
+	for i := range res {
+		res[i] = t0.Add(-p.Lead + time.Duration(i)*p.Interval)
+	}
+	return res
+}
+
+func (p AttemptPlan) String() string {
+	return fmt.Sprintf("%d 次，间隔 %s，提前 %s", p.Attempts, p.Interval, p.Lead)
+}
+
+// Plan : 场次生效的尝试计划，配置无效时退回默认计划（由 Validate 报告）
+func (s Session) Plan() AttemptPlan {
+	p, err := s.Burst.Plan()
+	if err != nil {
+		p, _ = (*Burst)(nil).Plan()
+	}
+	return p
+}
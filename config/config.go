@@ -1,153 +1,616 @@
 package config
 
 import (
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
 	"strconv"
-	"strings"
 	"sync"
 	"time"
+
+	"HighFrequencyTrading/catalog"
+	"HighFrequencyTrading/endpoint"
+	"HighFrequencyTrading/ledger"
+	"HighFrequencyTrading/secret"
+	"HighFrequencyTrading/sign"
+	"HighFrequencyTrading/store"
+	"HighFrequencyTrading/util"
 )
 
 const (
-	ExchangeLogFile  = "电信金豆换话费.log"
+	ExchangeLogFile  = store.LegacyExchangeLogFile
 	ExchangeLogFile2 = "电信金豆换话费2.log"
-	CacheFile        = "chinaTelecom_cache.json"
+	CacheFile        = store.CacheFile
+	KeyFile          = "telecom.key"             // 加密口令文件
+	VaultFile        = "telecom_secrets.json"    // 加密保存的账号密码
+	FingerprintFile  = "telecom_fingerprint.key" // 登录失败记录中凭证摘要的密钥
+	LedgerFile       = store.LedgerFile          // 逐次兑换尝试的账本
+	CatalogFile      = "catalog.json"            // 当天商品目录的缓存
 	DefaultMEXZ      = "0.5,5;1,10"
 )
 
-// Config : 存放命令行和环境变量的配置
+const (
+	DefaultKeepMonths   = 3                // 账本默认保留最近 3 个月，更早的归档
+	DefaultTicketTTL    = 12 * time.Hour   // ticket 默认有效期
+	TicketProbeInterval = 10 * time.Minute // 距上次验证不足该时长的 ticket 不再重复校验
+)
+
+// Config : 存放命令行、环境变量与配置文件合并后的配置
 type Config struct {
 	Jdhf string
 	MEXZ string
 	H    *int
+
+	ConfigFile string    // 使用的配置文件路径，为空表示未使用
+	Paths      Paths     // 当前 profile 的数据目录
+	Accounts   []Account // 最终生效的账号列表
+	Strategy   Strategy  // 最终生效的兑换策略
+	Sessions   []Session // 最终生效的场次列表
+	Session    string    // 强制场次名，为空时按当前时间选择
+	DryRun     bool      // 演练：兑换请求交给记录器，不写账本与运行历史
+	Storage    string    // 存储后端：json / sqlite
+
+	TicketTTL    time.Duration // 缓存 ticket 的有效期，0 表示不按时间过期
+	ProbeTickets bool          // 开场前是否校验缓存的 ticket
+
+	KeepMonths  int // 账本保留月数，更早的归档；0 表示不归档
+	PruneMonths int // 记录最长保留月数，更早的删除；0 表示永久保留
+
+	MaxLoginFailures int // 连续凭证错误达到该次数后暂停自动登录，0 表示不暂停
+
+	Endpoints endpoint.Endpoints // 对外接口的基础地址，已补全默认值
+	Protocol  sign.Protocol      // 生效的客户端协议 profile，已展开继承
+
+	CatalogFile string // 用户提供的商品目录文件，商城拉取失败时使用，为空表示未配置
+
+	Box    *secret.Box // 加密口令，未配置时为 nil（明文运行）
+	Notify Notify      // wxpusher 推送配置
+
+	sources map[string]Source // 配置项 -> 来源，用于 config show --effective
+
+	ticketTTLRaw string // 原始 TTL 配置，用于校验
+	protocolFile string // 协议覆盖文件的路径，未使用时为空
+
+	accountsFrom string   // 账号来源：jdhf 或配置文件路径，用于校验时定位
+	sealed       []string // 以 enc:v1: 形式写在配置中的值的位置，见 SealedValues
+	strategyFrom string   // 策略来源：MEXZ、配置文件路径或 default
 }
 
 // GlobalVars : 运行期的全局对象
 type GlobalVars struct {
 	Yf    string                         // 当前年月: 例如 "202503"
-	Dhjl  map[string]map[string][]string // 兑换日志：年月 -> (话费标题 -> []手机号)
-	Jp    map[string]map[string]string   // 商品映射
+	Dhjl  map[string]map[string][]string // 兑换日志：年月 -> (话费标题 -> []手机号)，由存储中的账本汇总而来
+	Jp    map[string]map[string]string   // 商品映射：场次名 -> (话费标题 -> activityId)
 	Wt    float64                        // 目标 UNIX 时间戳
 	Kswt  float64                        // 时间偏移量
 	Rs    int32
-	Cache map[string]string // 缓存结构：手机号 -> token
+	Cache map[string]store.Ticket // 缓存结构：手机号 -> ticket（已解密）
+
+	TicketTTL        time.Duration
+	ProbeTickets     bool
+	MaxLoginFailures int
+
+	Paths Paths       // 数据文件所在目录
+	Store store.Store // 兑换账本、ticket 缓存与运行历史
+	box   *secret.Box // 非 nil 时缓存中的 ticket 加密落盘
 
-	MorningExchanges   []string
-	AfternoonExchanges []string
+	secretsLock *util.FileLock // 口令的共享锁，见 LockSecrets
+
+	fpOnce sync.Once // 首次需要凭证摘要时加载 fpKey
+	fpKey  []byte
+	fpErr  error
+
+	Sessions []Session
+	Catalog  *catalog.Catalog       // 本场的商品目录，含所需金豆
+	Beans    map[string]BeanBalance // 手机号 -> 本场前后的金豆余额
+
+	DryRun bool // 演练：不写账本、不更新 Dhjl
 
 	Mu sync.RWMutex // 统一的读写锁
 }
 
-// NewConfig : 根据命令行参数和环境变量生成配置
-func NewConfig(cliJdhf, cliMEXZ string, cliH *int) *Config {
+// BeansUnknown : 未能查询到的金豆余额
+const BeansUnknown = -1
+
+// BeanBalance : 账号在本场兑换前后的金豆余额
+type BeanBalance struct {
+	Before int
+	After  int
+}
+
+// SetBeansBefore : 记录兑换前的金豆余额
+func (g *GlobalVars) SetBeansBefore(phone string, n int) {
+	g.Mu.Lock()
+	defer g.Mu.Unlock()
+	b, ok := g.Beans[phone]
+	if !ok {
+		b.After = BeansUnknown
+	}
+	b.Before = n
+	g.Beans[phone] = b
+}
+
+// SetBeansAfter : 记录兑换后的金豆余额
+func (g *GlobalVars) SetBeansAfter(phone string, n int) {
+	g.Mu.Lock()
+	defer g.Mu.Unlock()
+	b, ok := g.Beans[phone]
+	if !ok {
+		b.Before = BeansUnknown
+	}
+	b.After = n
+	g.Beans[phone] = b
+}
+
+// Options : 命令行传入的原始参数，空值表示未设置
+type Options struct {
+	Profile    string
+	DataDir    string
+	ConfigFile string
+	Jdhf       string
+	MEXZ       string
+	H          *int
+	Session    string
+	DryRun     bool
+}
+
+// NewConfig : 合并配置，优先级从高到低为：
+// 1. 环境变量 (jdhf / MEXZ / CTIME / TELECOM_SESSION / TELECOM_STORAGE / TELECOM_TICKET_TTL / TELECOM_ENDPOINT_* / TELECOM_CONFIG)
+// 2. 命令行参数
+// 3. 配置文件 (--config，未指定时使用 profile 目录下的 config.yaml)
+// 4. 默认值
+func NewConfig(opts Options) (*Config, error) {
 	cfg := &Config{
-		Jdhf: cliJdhf,
-		MEXZ: cliMEXZ,
-		H:    cliH,
+		Jdhf:       opts.Jdhf,
+		MEXZ:       opts.MEXZ,
+		H:          opts.H,
+		Session:    opts.Session,
+		ConfigFile: opts.ConfigFile,
+		DryRun:     opts.DryRun,
+		sources:    make(map[string]Source),
 	}
+	cfg.flagSource("jdhf", opts.Jdhf != "")
+	cfg.flagSource("MEXZ", opts.MEXZ != "")
+	cfg.flagSource("trade-hour", opts.H != nil)
+	cfg.flagSource("session", opts.Session != "")
+	cfg.flagSource("config", opts.ConfigFile != "")
 
 	// 如果有同名环境变量，则覆盖
 	if envJdhf := os.Getenv("jdhf"); envJdhf != "" {
 		cfg.Jdhf = envJdhf
+		cfg.sources["jdhf"] = envSource("jdhf")
 	}
 	if envMEXZ := os.Getenv("MEXZ"); envMEXZ != "" {
 		cfg.MEXZ = envMEXZ
+		cfg.sources["MEXZ"] = envSource("MEXZ")
 	}
 	if envH := os.Getenv("CTIME"); envH != "" {
 		if vv, err := strconv.Atoi(envH); err == nil {
 			cfg.H = &vv
+			cfg.sources["trade-hour"] = envSource("CTIME")
+		}
+	}
+	if envSession := os.Getenv("TELECOM_SESSION"); envSession != "" {
+		cfg.Session = envSession
+		cfg.sources["session"] = envSource("TELECOM_SESSION")
+	}
+	if envStorage := os.Getenv("TELECOM_STORAGE"); envStorage != "" {
+		cfg.Storage = envStorage
+		cfg.sources["storage"] = envSource("TELECOM_STORAGE")
+	}
+	if envFile := os.Getenv("TELECOM_CONFIG"); envFile != "" {
+		cfg.ConfigFile = envFile
+		cfg.sources["config"] = envSource("TELECOM_CONFIG")
+	}
+
+	// 数据目录：每个 profile 独立的配置、记录、缓存与日志
+	paths, err := ResolvePaths(opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := paths.Ensure(); err != nil {
+		return nil, err
+	}
+	cfg.Paths = paths
+	cfg.sources["profile"], cfg.sources["data-dir"] = pathSources(opts)
+	if cfg.ConfigFile == "" {
+		var found bool
+		if cfg.ConfigFile, found = paths.ConfigFile(); found {
+			cfg.sources["config"] = Source{Kind: SourceDefault, Name: "profile"}
+		}
+	}
+
+	// 配置文件为最低优先级的来源
+	var fc *FileConfig
+	if cfg.ConfigFile != "" {
+		loaded, err := LoadFile(cfg.ConfigFile)
+		if err != nil {
+			return nil, err
+		}
+		fc = loaded
+	}
+
+	// 账号：jdhf 一旦设置即整体覆盖配置文件中的账号
+	if cfg.Jdhf != "" {
+		accounts, err := ParseJdhf(cfg.Jdhf)
+		if err != nil {
+			log.Printf("[Warn] %v", err)
 		}
+		cfg.Accounts = accounts
+		cfg.accountsFrom = "jdhf"
+		cfg.sources["accounts"] = cfg.sources["jdhf"]
+	} else if fc != nil {
+		cfg.Accounts = fc.Accounts
+		cfg.accountsFrom = cfg.ConfigFile
+		cfg.sources["accounts"] = fileSource(cfg.ConfigFile)
 	}
 
-	if cfg.MEXZ == "" {
+	// 策略：MEXZ 覆盖配置文件中的 strategy
+	switch {
+	case cfg.MEXZ != "":
+		st, err := ParseMEXZ(cfg.MEXZ)
+		if err != nil {
+			log.Printf("[Warn] %v, 使用默认配置", err)
+			st, _ = ParseMEXZ(DefaultMEXZ)
+		}
+		cfg.Strategy = st
+		cfg.strategyFrom = "MEXZ"
+		cfg.sources["strategy"] = cfg.sources["MEXZ"]
+	case fc != nil && fc.Strategy != nil:
+		cfg.Strategy = *fc.Strategy
+		cfg.MEXZ = cfg.Strategy.MEXZ()
+		cfg.strategyFrom = cfg.ConfigFile
+		cfg.sources["strategy"] = fileSource(cfg.ConfigFile)
+		cfg.sources["MEXZ"] = fileSource(cfg.ConfigFile)
+	default:
 		cfg.MEXZ = DefaultMEXZ
+		cfg.strategyFrom = "default"
+		cfg.Strategy, _ = ParseMEXZ(DefaultMEXZ)
+		cfg.sources["strategy"] = Source{Kind: SourceDefault}
+		cfg.sources["MEXZ"] = Source{Kind: SourceDefault}
+	}
+	if cfg.H == nil && cfg.Strategy.TradeHour != nil {
+		cfg.H = cfg.Strategy.TradeHour
+		cfg.sources["trade-hour"] = fileSource(cfg.ConfigFile)
+	}
+	if cfg.Session == "" && cfg.Strategy.Session != "" {
+		cfg.Session = cfg.Strategy.Session
+		cfg.sources["session"] = fileSource(cfg.ConfigFile)
+	}
+	cfg.Sessions = cfg.Strategy.SessionList()
+
+	// 存储后端：环境变量 → 配置文件 → json
+	switch {
+	case cfg.Storage != "":
+	case fc != nil && fc.Storage != "":
+		cfg.Storage = fc.Storage
+		cfg.sources["storage"] = fileSource(cfg.ConfigFile)
+	default:
+		cfg.Storage = store.BackendJSON
+	}
+
+	// ticket 缓存：TTL 环境变量 → 配置文件 → 默认值
+	cfg.TicketTTL, cfg.ProbeTickets = DefaultTicketTTL, true
+	ttlSrc := envSource("TELECOM_TICKET_TTL")
+	cfg.ticketTTLRaw = os.Getenv("TELECOM_TICKET_TTL")
+	if cfg.ticketTTLRaw == "" && fc != nil && fc.Cache != nil {
+		cfg.ticketTTLRaw, ttlSrc = fc.Cache.TTL, fileSource(cfg.ConfigFile)
+	}
+	if cfg.ticketTTLRaw != "" {
+		if ttl, err := time.ParseDuration(cfg.ticketTTLRaw); err != nil || ttl < 0 {
+			log.Printf("[Warn] ticket 有效期 %q 无效, 使用默认值 %s", cfg.ticketTTLRaw, DefaultTicketTTL)
+		} else {
+			cfg.TicketTTL = ttl
+			cfg.sources["cache.ttl"] = ttlSrc
+		}
+	}
+	if fc != nil && fc.Cache != nil && fc.Cache.Probe != nil {
+		cfg.ProbeTickets = *fc.Cache.Probe
+		cfg.sources["cache.probe"] = fileSource(cfg.ConfigFile)
+	}
+
+	// 保留策略：配置文件 → 默认值
+	cfg.KeepMonths = DefaultKeepMonths
+	if fc != nil && fc.Retention != nil {
+		if fc.Retention.KeepMonths != nil {
+			cfg.KeepMonths = *fc.Retention.KeepMonths
+			cfg.sources["retention.keepMonths"] = fileSource(cfg.ConfigFile)
+		}
+		if fc.Retention.PruneMonths != nil {
+			cfg.PruneMonths = *fc.Retention.PruneMonths
+			cfg.sources["retention.pruneMonths"] = fileSource(cfg.ConfigFile)
+		}
+	}
+
+	// 登录保护：配置文件 → 默认值
+	cfg.MaxLoginFailures = DefaultMaxLoginFailures
+	if fc != nil && fc.Login != nil && fc.Login.MaxFailures != nil {
+		cfg.MaxLoginFailures = *fc.Login.MaxFailures
+		cfg.sources["login.maxFailures"] = fileSource(cfg.ConfigFile)
+	}
+
+	// 接口地址：逐项按 环境变量 → 配置文件 → 默认值，并立即生效于本进程的所有对外请求
+	cfg.Endpoints = loadEndpoints(cfg, fc)
+	endpoint.Set(cfg.Endpoints)
+
+	// 客户端协议：按 环境变量 → 配置文件 → 内置默认 选择 profile，同样立即生效
+	protocol, err := loadProtocol(cfg, fc)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Protocol = protocol
+	sign.SetProtocol(protocol)
+
+	// 商品目录文件：环境变量 → 配置文件，相对路径按配置文件所在目录解析
+	if fc != nil && fc.Catalog != nil && fc.Catalog.File != "" {
+		cfg.CatalogFile = fc.Catalog.File
+		if !filepath.IsAbs(cfg.CatalogFile) {
+			cfg.CatalogFile = filepath.Join(filepath.Dir(cfg.ConfigFile), cfg.CatalogFile)
+		}
+		cfg.sources["catalog.file"] = fileSource(cfg.ConfigFile)
+	}
+	if v := os.Getenv("TELECOM_CATALOG_FILE"); v != "" {
+		cfg.CatalogFile = v
+		cfg.sources["catalog.file"] = envSource("TELECOM_CATALOG_FILE")
+	}
+
+	// 密码：支持 enc:v1: 加密值，或留空从保险库读取
+	box, err := secret.Load(paths.KeyFile())
+	if err != nil {
+		return nil, err
+	}
+	cfg.Box = box
+	cfg.sources["secrets.key"] = keySource(paths)
+	if err := resolvePasswords(cfg); err != nil {
+		return nil, err
+	}
+
+	// 推送：环境变量优先，其次为 profile 目录（或当前目录）下的 wxpusher.yaml
+	if err := loadNotify(cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// endpointEnvs : 接口地址对应的环境变量
+var endpointEnvs = []struct {
+	key, env string
+	field    func(*endpoint.Endpoints) *string
+}{
+	{"endpoints.login", "TELECOM_ENDPOINT_LOGIN", func(e *endpoint.Endpoints) *string { return &e.Login }},
+	{"endpoints.ticket", "TELECOM_ENDPOINT_TICKET", func(e *endpoint.Endpoints) *string { return &e.Ticket }},
+	{"endpoints.mall", "TELECOM_ENDPOINT_MALL", func(e *endpoint.Endpoints) *string { return &e.Mall }},
+	{"endpoints.wxpusher", "TELECOM_ENDPOINT_WXPUSHER", func(e *endpoint.Endpoints) *string { return &e.WxPusher }},
+}
+
+// loadEndpoints : 合并接口地址并记录来源
+func loadEndpoints(cfg *Config, fc *FileConfig) endpoint.Endpoints {
+	var e endpoint.Endpoints
+	for _, it := range endpointEnvs {
+		field := it.field(&e)
+		if v := os.Getenv(it.env); v != "" {
+			*field = v
+			cfg.sources[it.key] = envSource(it.env)
+		} else if fc != nil && fc.Endpoints != nil && *it.field(fc.Endpoints) != "" {
+			*field = *it.field(fc.Endpoints)
+			cfg.sources[it.key] = fileSource(cfg.ConfigFile)
+		}
+	}
+	return e.WithDefaults()
+}
+
+// loadProtocol : 读取协议覆盖文件并解析选中的 profile，相对路径按配置文件所在目录解析
+func loadProtocol(cfg *Config, fc *FileConfig) (sign.Protocol, error) {
+	var name, file string
+	if fc != nil && fc.Protocol != nil {
+		if fc.Protocol.Profile != "" {
+			name = fc.Protocol.Profile
+			cfg.sources["protocol.profile"] = fileSource(cfg.ConfigFile)
+		}
+		if fc.Protocol.File != "" {
+			file = fc.Protocol.File
+			if !filepath.IsAbs(file) {
+				file = filepath.Join(filepath.Dir(cfg.ConfigFile), file)
+			}
+			cfg.sources["protocol.file"] = fileSource(cfg.ConfigFile)
+		}
+	}
+	if v := os.Getenv("TELECOM_PROTOCOL"); v != "" {
+		name = v
+		cfg.sources["protocol.profile"] = envSource("TELECOM_PROTOCOL")
+	}
+	if v := os.Getenv("TELECOM_PROTOCOL_FILE"); v != "" {
+		file = v
+		cfg.sources["protocol.file"] = envSource("TELECOM_PROTOCOL_FILE")
+	}
+	cfg.protocolFile = file
+
+	set, err := sign.LoadProtocols(file)
+	if err != nil {
+		return sign.Protocol{}, fmt.Errorf("加载协议文件失败: %w", err)
+	}
+	return set.Resolve(name)
+}
+
+// EnabledAccounts : 返回启用的账号
+func (cfg *Config) EnabledAccounts() []Account {
+	var res []Account
+	for _, acc := range cfg.Accounts {
+		if acc.IsEnabled() {
+			res = append(res, acc)
+		}
+	}
+	return res
+}
+
+// OpenStore : 打开配置的存储后端；首次打开时导入数据目录中的旧版兑换日志与缓存，默认 profile 还导入当前目录中的
+func (cfg *Config) OpenStore() (store.Store, error) {
+	st, err := store.Open(cfg.Storage, cfg.Paths.Dir)
+	if err != nil {
+		return nil, err
+	}
+	res, err := store.ImportLegacy(st, cfg.Paths.Dir, false, cfg.Paths.LegacyDirs()...)
+	if err != nil {
+		st.Close()
+		return nil, fmt.Errorf("导入旧数据失败: %w", err)
+	}
+	if !res.Skipped && (res.Records > 0 || res.Tickets > 0) {
+		log.Printf("[Store] 已导入旧数据: %d 条兑换记录, %d 个缓存 ticket", res.Records, res.Tickets)
+	}
+	return st, nil
+}
+
+// ApplyRetention : 按配置的保留策略归档、清理兑换记录
+func (cfg *Config) ApplyRetention(st store.Store, now time.Time) error {
+	if cfg.KeepMonths <= 0 && cfg.PruneMonths <= 0 {
+		return nil
+	}
+	res, err := store.ApplyRetention(st, cfg.Paths.ArchiveDir(), cfg.KeepMonths, cfg.PruneMonths, now)
+	if err != nil {
+		return err
 	}
-	return cfg
+	if res.Archived > 0 {
+		log.Printf("[Store] 已归档 %d 条兑换记录: %v", res.Archived, res.ArchivedMonths)
+	}
+	if res.Pruned > 0 {
+		log.Printf("[Store] 已删除 %d 条超过保留期限的兑换记录", res.Pruned)
+	}
+	return nil
 }
 
-// InitGlobalVars : 初始化全局变量
-func InitGlobalVars(cfg *Config) *GlobalVars {
+// InitGlobalVars : 初始化全局变量，使用完毕后调用 Close
+func InitGlobalVars(cfg *Config) (*GlobalVars, error) {
+	st, err := cfg.OpenStore()
+	if err != nil {
+		return nil, err
+	}
+	lock, err := LockSecrets(cfg.Paths, true)
+	if err != nil {
+		st.Close()
+		return nil, err
+	}
+	// 读取配置后、取得锁前口令可能刚被轮换，此时缓存已改用新口令加密
+	if box, err := secret.Load(cfg.Paths.KeyFile()); err == nil && !box.Equal(cfg.Box) {
+		lock.Unlock()
+		st.Close()
+		return nil, fmt.Errorf("加密口令已被轮换，请重新运行")
+	}
+	// 先按保留策略归档旧记录，Dhjl 只汇总账本中保留的月份；演练不改动账本
+	if !cfg.DryRun {
+		if err := cfg.ApplyRetention(st, time.Now()); err != nil {
+			log.Printf("[Warn] 归档兑换记录失败: %v", err)
+		}
+	}
 	g := &GlobalVars{
 		Dhjl:  make(map[string]map[string][]string),
-		Jp:    map[string]map[string]string{"10": {}, "14": {}},
-		Cache: make(map[string]string),
+		Jp:    make(map[string]map[string]string),
+		Cache: make(map[string]store.Ticket),
+		Beans: make(map[string]BeanBalance),
+		Paths: cfg.Paths,
+		Store: st,
+		box:   cfg.Box,
+
+		secretsLock: lock,
+
+		TicketTTL:        cfg.TicketTTL,
+		ProbeTickets:     cfg.ProbeTickets,
+		MaxLoginFailures: cfg.MaxLoginFailures,
+		DryRun:           cfg.DryRun,
 	}
 
 	g.Yf = time.Now().Format("200601")
 	g.Kswt = 0.1
 
-	// 1. 读取兑换日志
-	dat, err := ioutil.ReadFile(ExchangeLogFile)
-	if err == nil {
-		var tmp map[string]map[string][]string
-		if json.Unmarshal(dat, &tmp) == nil {
-			// 写入 Dhjl 需要加写锁，但此处 g 尚未被多协程共享
-			g.Dhjl = tmp
-		}
+	// 1. 由账本中的成功记录汇总兑换日志
+	records, err := st.Records()
+	if err != nil {
+		g.Close()
+		return nil, fmt.Errorf("读取兑换账本失败: %w", err)
 	}
+	// 写入 Dhjl 需要加写锁，但此处 g 尚未被多协程共享
+	g.Dhjl = ledger.MonthView(records)
 	// 确保有当前月份key
 	if _, ok := g.Dhjl[g.Yf]; !ok {
 		g.Dhjl[g.Yf] = make(map[string][]string)
 	}
 
 	// 2. 加载缓存
-	dat2, err := ioutil.ReadFile(CacheFile)
-	if err == nil {
-		var c map[string]string
-		if json.Unmarshal(dat2, &c) == nil {
-			g.Cache = c
-		}
+	c, err := st.Cache()
+	if err != nil {
+		log.Printf("[Warn] 读取缓存失败，将重新登录: %v", err)
+	} else {
+		g.Cache = openCache(g.box, c)
 	}
 
-	// 3. 解析 MEXZ
-	parts := strings.Split(cfg.MEXZ, ";")
-	if len(parts) == 2 {
-		g.MorningExchanges = parseExchanges(parts[0])
-		g.AfternoonExchanges = parseExchanges(parts[1])
-	} else {
-		log.Println("[Warn] MEXZ 格式不正确, 使用默认配置")
-		g.MorningExchanges = parseExchanges("0.5,5")
-		g.AfternoonExchanges = parseExchanges("1,10")
+	// 3. 兑换场次（已在 NewConfig 中解析），g.Jp 按场次名分组
+	g.Sessions = cfg.Sessions
+	for _, s := range g.Sessions {
+		g.Jp[s.Name] = make(map[string]string)
 	}
 
-	return g
+	return g, nil
+}
+
+// Close : 关闭存储
+func (g *GlobalVars) Close() error {
+	g.secretsLock.Unlock()
+	if g.Store == nil {
+		return nil
+	}
+	return g.Store.Close()
 }
 
-// parseExchanges : 将 "0.5,5,6" 转成 ["0.5元话费","5元话费","6元话费"]
-func parseExchanges(raw string) []string {
-	arr := strings.Split(raw, ",")
+// LockSecrets : 口令与其加密的保险库、ticket 缓存的锁。用当前口令读写这些数据的进程持有共享锁（shared），
+// 可同时运行；重新加密时取排他锁，有实例在运行时立即返回 util.ErrLocked，避免其把旧口令加密的 ticket 写回缓存
+func LockSecrets(p Paths, shared bool) (*util.FileLock, error) {
+	if shared {
+		return util.LockShared(p.Vault())
+	}
+	return util.TryLock(p.Vault())
+}
+
+// parseExchanges : 将 ["0.5","5","6"] 转成 ["0.5元话费","5元话费","6元话费"]
+func parseExchanges(items []string) []string {
 	var res []string
-	for _, it := range arr {
-		res = append(res, it+"元话费")
+	for _, it := range items {
+		res = append(res, ItemTitle(it))
 	}
 	return res
 }
 
-// SaveDhjl : 将兑换日志保存到文件
-func (g *GlobalVars) SaveDhjl() {
-	// 读锁即可，因为我们只需要读取 Dhjl 并序列化
-	g.Mu.RLock()
-	bt, _ := json.Marshal(g.Dhjl)
-	g.Mu.RUnlock()
+// PutCache : 更新内存中的 ticket 并写入存储，配置了口令时加密保存
+func (g *GlobalVars) PutCache(phone string, t store.Ticket) error {
+	g.Mu.Lock()
+	g.Cache[phone] = t
+	g.Mu.Unlock()
 
-	_ = ioutil.WriteFile(ExchangeLogFile, bt, 0644)
+	sealed, err := sealTicket(g.box, t.Ticket)
+	if err != nil {
+		return fmt.Errorf("缓存加密失败: %w", err)
+	}
+	if g.Store == nil {
+		return nil
+	}
+	t.Ticket = sealed
+	return g.Store.PutCache(phone, t)
 }
 
-// SaveCache : 将缓存保存到文件
-func (g *GlobalVars) SaveCache() {
-	// 同理，读锁
-	g.Mu.RLock()
-	bt, _ := json.Marshal(g.Cache)
-	g.Mu.RUnlock()
+// DeleteCache : 移除失效的 ticket
+func (g *GlobalVars) DeleteCache(phone string) error {
+	g.Mu.Lock()
+	delete(g.Cache, phone)
+	g.Mu.Unlock()
 
-	_ = ioutil.WriteFile(CacheFile, bt, 0644)
+	if g.Store == nil {
+		return nil
+	}
+	return g.Store.DeleteCache(phone)
 }
 
 // Debug : 调试用
 func (cfg *Config) Debug() {
-	fmt.Printf("[DEBUG] jdhf=%s MEXZ=%s H=%v\n", cfg.Jdhf, cfg.MEXZ, cfg.H)
+	for _, v := range cfg.Effective() {
+		fmt.Printf("[DEBUG] %s=%s (%s)\n", v.Key, v.Value, v.Source)
+	}
 }
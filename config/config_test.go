@@ -0,0 +1,519 @@
+// config_test.go
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"HighFrequencyTrading/endpoint"
+	"HighFrequencyTrading/ledger"
+	"HighFrequencyTrading/secret"
+	"HighFrequencyTrading/sign"
+	"HighFrequencyTrading/store"
+	"HighFrequencyTrading/util"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestNewConfigFromYAML(t *testing.T) {
+	t.Setenv("TELECOM_DATA_DIR", t.TempDir())
+	t.Setenv("jdhf", "")
+	t.Setenv("MEXZ", "")
+	t.Setenv("CTIME", "")
+	path := writeFile(t, "telecom.yaml", `
+accounts:
+  - phone: "13800138000"
+    password: "p#ss&word"
+    notifyUid: "UID_1"
+  - phone: "13900139000"
+    password: "123456"
+    enabled: false
+strategy:
+  tradeHour: 14
+  morning: ["0.5", "5"]
+  afternoon: ["10"]
+`)
+
+	cfg, err := NewConfig(Options{ConfigFile: path})
+	if err != nil {
+		t.Fatalf("NewConfig 返回错误: %v", err)
+	}
+	if len(cfg.Accounts) != 2 {
+		t.Fatalf("预期 2 个账号，实际 %d", len(cfg.Accounts))
+	}
+	if cfg.Accounts[0].Password != "p#ss&word" {
+		t.Errorf("密码被错误拆分: %q", cfg.Accounts[0].Password)
+	}
+	if got := cfg.EnabledAccounts(); len(got) != 1 || got[0].UID() != "UID_1" {
+		t.Errorf("启用账号不符: %+v", got)
+	}
+	if cfg.MEXZ != "0.5,5;10" {
+		t.Errorf("预期 MEXZ=0.5,5;10，实际 %s", cfg.MEXZ)
+	}
+	if cfg.H == nil || *cfg.H != 14 {
+		t.Errorf("预期 tradeHour 来自配置文件")
+	}
+}
+
+func TestNewConfigFromTOML(t *testing.T) {
+	t.Setenv("TELECOM_DATA_DIR", t.TempDir())
+	t.Setenv("jdhf", "")
+	t.Setenv("MEXZ", "")
+	path := writeFile(t, "telecom.toml", `
+[[accounts]]
+phone = "13800138000"
+password = "123456"
+items = ["5"]
+`)
+
+	cfg, err := NewConfig(Options{ConfigFile: path})
+	if err != nil {
+		t.Fatalf("NewConfig 返回错误: %v", err)
+	}
+	if len(cfg.Accounts) != 1 || !cfg.Accounts[0].WantsItem("5元话费") || cfg.Accounts[0].WantsItem("10元话费") {
+		t.Errorf("账号商品筛选不符: %+v", cfg.Accounts)
+	}
+	if cfg.MEXZ != DefaultMEXZ {
+		t.Errorf("未配置策略时应使用默认值，实际 %s", cfg.MEXZ)
+	}
+}
+
+func TestNewConfigPrecedence(t *testing.T) {
+	t.Setenv("TELECOM_DATA_DIR", t.TempDir())
+	path := writeFile(t, "telecom.yaml", `
+accounts:
+  - phone: "13800138000"
+    password: "123456"
+strategy:
+  morning: ["0.5"]
+  afternoon: ["1"]
+`)
+
+	// 命令行覆盖配置文件
+	t.Setenv("jdhf", "")
+	t.Setenv("MEXZ", "")
+	cfg, err := NewConfig(Options{ConfigFile: path, MEXZ: "5;10"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.MEXZ != "5;10" || len(cfg.Accounts) != 1 {
+		t.Errorf("命令行 MEXZ 未覆盖配置文件: %s", cfg.MEXZ)
+	}
+
+	// 环境变量覆盖命令行
+	t.Setenv("jdhf", "13700137000#654321#UID_2&13600136000#111111")
+	t.Setenv("MEXZ", "6;3")
+	cfg, err = NewConfig(Options{ConfigFile: path, MEXZ: "5;10"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.MEXZ != "6;3" {
+		t.Errorf("环境变量 MEXZ 未覆盖命令行: %s", cfg.MEXZ)
+	}
+	if len(cfg.Accounts) != 2 || cfg.Accounts[0].UID() != "UID_2" || cfg.Accounts[1].UID() != "13600136000" {
+		t.Errorf("jdhf 未覆盖配置文件账号: %+v", cfg.Accounts)
+	}
+}
+
+func TestLoadFileUnknownField(t *testing.T) {
+	path := writeFile(t, "telecom.yaml", "acounts: []\n")
+	if _, err := LoadFile(path); err == nil {
+		t.Error("预期未知字段报错")
+	}
+}
+
+func TestEffectiveSourcesAndMasking(t *testing.T) {
+	t.Setenv("TELECOM_DATA_DIR", t.TempDir())
+	t.Setenv("jdhf", "")
+	t.Setenv("MEXZ", "")
+	t.Setenv("CTIME", "10")
+	t.Setenv("WXPUSHER_APP_TOKEN", "AT_fg9ETrNBSf0UwqSWTJMU6nCUyIKzrEz0")
+	t.Setenv("WXPUSHER_UID", "")
+	path := writeFile(t, "telecom.yaml", `
+accounts:
+  - phone: "13800138000"
+    password: "secret-pwd"
+`)
+
+	cfg, err := NewConfig(Options{ConfigFile: path, MEXZ: "5;10"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]Value)
+	for _, v := range cfg.Effective() {
+		got[v.Key] = v
+	}
+
+	want := map[string]struct{ value, kind string }{
+		"config":               {path, SourceFlag},
+		"accounts[0].phone":    {"13800138000", SourceFile},
+		"accounts[0].password": {"******", SourceFile},
+		"MEXZ":                 {"5;10", SourceFlag},
+		"trade-hour":           {"10", SourceEnv},
+		"notify.appToken":      {"AT_f****rEz0", SourceEnv},
+	}
+	for key, w := range want {
+		v, ok := got[key]
+		if !ok {
+			t.Errorf("缺少配置项 %s", key)
+			continue
+		}
+		if v.Value != w.value || v.Source.Kind != w.kind {
+			t.Errorf("%s: 预期 %s (%s)，实际 %s (%s)", key, w.value, w.kind, v.Value, v.Source)
+		}
+	}
+	for _, v := range got {
+		if v.Value == "secret-pwd" {
+			t.Errorf("%s 泄露了明文密码", v.Key)
+		}
+	}
+}
+
+func TestGlobalVarsShareStore(t *testing.T) {
+	for _, backend := range []string{store.BackendJSON, store.BackendSQLite} {
+		t.Run(backend, func(t *testing.T) {
+			cfg := &Config{Paths: Paths{Profile: DefaultProfile, Dir: t.TempDir()}, Storage: backend}
+
+			// 两个进程在同一目录下各自启动，分别兑换成功后保存
+			g1, err := InitGlobalVars(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			g2, err := InitGlobalVars(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, g := range []*GlobalVars{g1, g2} {
+				phone := []string{"13800138000", "13900139000"}[i]
+				if err := g.Store.Append(ledger.Record{Time: time.Now(), Phone: phone, Item: "5元话费", Outcome: ledger.OutcomeSuccess}); err != nil {
+					t.Fatal(err)
+				}
+				if err := g.PutCache(phone, store.Ticket{Ticket: "ticket-" + phone, IssuedAt: time.Now()}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			g1.Close()
+			g2.Close()
+
+			g3, err := InitGlobalVars(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer g3.Close()
+			if phones := g3.Dhjl[g3.Yf]["5元话费"]; len(phones) != 2 {
+				t.Errorf("兑换日志应包含两个进程的记录: %v", phones)
+			}
+			if len(g3.Cache) != 2 {
+				t.Errorf("缓存应包含两个进程的 ticket: %v", g3.Cache)
+			}
+		})
+	}
+}
+
+func TestInitGlobalVarsImportsLegacyFiles(t *testing.T) {
+	dir := t.TempDir()
+	legacyLog := `{"202501":{"5元话费":["13800138000","13900139000"]}}`
+	if err := os.WriteFile(filepath.Join(dir, ExchangeLogFile), []byte(legacyLog), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, CacheFile), []byte(`{"13800138000":"old-ticket"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &Config{Paths: Paths{Profile: DefaultProfile, Dir: dir}, Storage: store.BackendSQLite}
+
+	for i := 0; i < 2; i++ {
+		g, err := InitGlobalVars(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		records, err := g.Store.Records()
+		g.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 第二次打开不应重复导入
+		if len(records) != 2 {
+			t.Fatalf("第 %d 次打开后记录数 = %d, 期望 2", i+1, len(records))
+		}
+		if g.Cache["13800138000"].Ticket != "old-ticket" || len(g.Dhjl["202501"]["5元话费"]) != 2 {
+			t.Errorf("旧数据未导入: cache=%v dhjl=%v", g.Cache, g.Dhjl)
+		}
+	}
+}
+
+// 旧版本把数据写在工作目录；任意 profile 的存储首次打开时都从工作目录导入
+func TestOpenStoreImportsLegacyFilesForDefaultProfile(t *testing.T) {
+	cwd := t.TempDir()
+	if err := os.WriteFile(filepath.Join(cwd, ExchangeLogFile), []byte(`{"202501":{"5元话费":["13800138000"]}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(cwd, CacheFile), []byte(`{"13800138000":"old-ticket"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(cwd); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	// 已存在的默认数据目录导入工作目录中的旧数据
+	cfg := &Config{Paths: Paths{Profile: DefaultProfile, Dir: t.TempDir()}, Storage: store.BackendJSON}
+	g, err := InitGlobalVars(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	if g.Cache["13800138000"].Ticket != "old-ticket" || len(g.Dhjl["202501"]["5元话费"]) != 1 {
+		t.Errorf("未从工作目录导入旧数据: cache=%v dhjl=%v", g.Cache, g.Dhjl)
+	}
+
+	// 其它 profile 不继承工作目录中另一组账号的数据
+	other, err := InitGlobalVars(&Config{Paths: Paths{Profile: "family", Dir: t.TempDir()}, Storage: store.BackendJSON})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	if len(other.Cache) != 0 || len(other.Dhjl["202501"]) != 0 {
+		t.Errorf("非默认 profile 不应导入工作目录的旧数据: cache=%v dhjl=%v", other.Cache, other.Dhjl)
+	}
+}
+
+func TestNewConfigEndpoints(t *testing.T) {
+	t.Setenv("TELECOM_DATA_DIR", t.TempDir())
+	t.Setenv("jdhf", "")
+	t.Setenv("MEXZ", "")
+	t.Setenv("TELECOM_ENDPOINT_MALL", "http://127.0.0.1:9001/")
+	path := writeFile(t, "telecom.yaml", `
+accounts:
+  - phone: "13800138000"
+    password: "123456"
+endpoints:
+  login: http://stub.local:9031
+  mall: http://ignored.local
+`)
+	defer endpoint.Set(endpoint.Default())
+
+	cfg, err := NewConfig(Options{ConfigFile: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := endpoint.Endpoints{
+		Login:    "http://stub.local:9031",
+		Ticket:   endpoint.DefaultTicket,
+		Mall:     "http://127.0.0.1:9001",
+		WxPusher: endpoint.DefaultWxPusher,
+	}
+	if cfg.Endpoints != want {
+		t.Errorf("Endpoints = %+v，期望 %+v", cfg.Endpoints, want)
+	}
+	if got := endpoint.Mall("/x"); got != "http://127.0.0.1:9001/x" {
+		t.Errorf("配置未生效: %s", got)
+	}
+	if src := cfg.sources["endpoints.mall"]; src.Kind != SourceEnv {
+		t.Errorf("endpoints.mall 来源应为环境变量: %v", src)
+	}
+
+	cfg.Endpoints.Ticket = "appgologin.189.cn"
+	if problems := Validate(cfg, testItems); len(problems) != 1 || problems[0].Location != "endpoints.ticket" {
+		t.Errorf("应只报告 endpoints.ticket: %v", problems)
+	}
+}
+
+func TestNewConfigProtocol(t *testing.T) {
+	t.Setenv("TELECOM_DATA_DIR", t.TempDir())
+	t.Setenv("jdhf", "")
+	t.Setenv("MEXZ", "")
+	protoFile := writeFile(t, "protocols.yaml", `
+profiles:
+  ios-9.7.0:
+    extends: ios-9.6.1
+    clientType: "#9.7.0#channel50#iPhone 15 Pro#"
+`)
+	// 相对路径按配置文件所在目录解析
+	path := filepath.Join(filepath.Dir(protoFile), "telecom.yaml")
+	err := os.WriteFile(path, []byte(`
+accounts:
+  - phone: "13800138000"
+    password: "123456"
+protocol:
+  profile: ios-9.7.0
+  file: protocols.yaml
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sign.SetProtocol(sign.CurrentProtocol())
+
+	cfg, err := NewConfig(Options{ConfigFile: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Protocol.Name != "ios-9.7.0" || sign.CurrentProtocol().ClientType != "#9.7.0#channel50#iPhone 15 Pro#" {
+		t.Errorf("协议 profile 未生效: %+v", cfg.Protocol)
+	}
+
+	t.Setenv("TELECOM_PROTOCOL", "android-1.0")
+	if _, err := NewConfig(Options{ConfigFile: path}); err == nil {
+		t.Error("不存在的 profile 应报错")
+	}
+}
+
+func TestLoginLockout(t *testing.T) {
+	cfg := &Config{Paths: Paths{Profile: DefaultProfile, Dir: t.TempDir()}, MaxLoginFailures: 3}
+	g, err := InitGlobalVars(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+
+	acc := Account{Phone: "13800138000", Password: "123456"}
+	wrong := &sign.LoginError{Kind: sign.ErrWrongPassword, Code: "8105"}
+	now := time.Now()
+
+	// 服务暂不可用不计入
+	if _, suspended, err := g.RecordLoginFailure(acc, &sign.LoginError{Kind: sign.ErrServiceUnavailable}, now); err != nil || suspended {
+		t.Fatalf("暂不可用不应记录: %v %v", suspended, err)
+	}
+	if reason, _ := g.LoginBlocked(acc, now); reason != "" {
+		t.Fatalf("暂不可用后不应阻止登录: %s", reason)
+	}
+	// 本地检查出的凭证格式错误没有发出请求，同样不计入
+	if _, err := sign.UserLoginNormal(acc.Phone, "123"); !errors.Is(err, sign.ErrInvalidCredential) {
+		t.Fatalf("过短的密码应报 ErrInvalidCredential: %v", err)
+	} else if _, suspended, err := g.RecordLoginFailure(acc, err, now); err != nil || suspended {
+		t.Fatalf("格式错误不应记录: %v %v", suspended, err)
+	}
+	if reason, _ := g.LoginBlocked(acc, now); reason != "" {
+		t.Fatalf("格式错误后不应阻止登录: %s", reason)
+	}
+	// 输错短信验证码同样不计入
+	if _, err := sign.UserLoginSMS(acc.Phone, "12ab"); !errors.Is(err, sign.ErrInvalidInput) {
+		t.Fatalf("格式不正确的验证码应报 ErrInvalidInput: %v", err)
+	} else if _, suspended, err := g.RecordLoginFailure(acc, err, now); err != nil || suspended {
+		t.Fatalf("输入错误不应记录: %v %v", suspended, err)
+	}
+	if states, _ := g.Store.LoginStates(); len(states) != 0 {
+		t.Fatalf("输入错误不应记录: %+v", states)
+	}
+
+	for i := 1; i <= 3; i++ {
+		st, suspended, err := g.RecordLoginFailure(acc, wrong, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if suspended != (i == 3) || st.CredentialFailures != i {
+			t.Fatalf("第 %d 次: suspended=%v state=%+v", i, suspended, st)
+		}
+		if want := now.Add(LoginBackoff(i)); !st.NextAttempt.Equal(want) {
+			t.Errorf("第 %d 次退避到 %v，期望 %v", i, st.NextAttempt, want)
+		}
+	}
+	// 退避期过后仍处于暂停状态
+	if reason, _ := g.LoginBlocked(acc, now.Add(48*time.Hour)); reason == "" {
+		t.Fatal("暂停后应阻止登录")
+	}
+
+	// 暂停与加密口令无关：换用或去掉口令后仍处于暂停状态
+	for _, key := range []string{"rotated-key", ""} {
+		t.Setenv(secret.EnvKey, key)
+		box, err := secret.Load(cfg.Paths.KeyFile())
+		if err != nil {
+			t.Fatal(err)
+		}
+		other, err := InitGlobalVars(&Config{Paths: cfg.Paths, Box: box, MaxLoginFailures: 3})
+		if err != nil {
+			t.Fatal(err)
+		}
+		reason, err := other.LoginBlocked(acc, now)
+		other.Close()
+		if err != nil || reason == "" {
+			t.Fatalf("口令变化后仍应暂停: %q %v", reason, err)
+		}
+	}
+
+	// 修改密码后自动恢复
+	acc.Password = "654321"
+	if reason, err := g.LoginBlocked(acc, now); err != nil || reason != "" {
+		t.Fatalf("凭证修改后应允许登录: %q %v", reason, err)
+	}
+	if states, _ := g.Store.LoginStates(); len(states) != 0 {
+		t.Errorf("凭证修改后应清除记录: %+v", states)
+	}
+
+	// 账号锁定立即暂停，登录成功清除记录
+	if _, suspended, _ := g.RecordLoginFailure(acc, &sign.LoginError{Kind: sign.ErrAccountLocked}, now); !suspended {
+		t.Error("账号锁定应立即暂停")
+	}
+	if err := g.RecordLoginSuccess(acc.Phone); err != nil {
+		t.Fatal(err)
+	}
+	if reason, _ := g.LoginBlocked(acc, now); reason != "" {
+		t.Errorf("登录成功后不应阻止: %s", reason)
+	}
+
+	if LoginBackoff(1) != LoginBackoffBase || LoginBackoff(100) != LoginBackoffMax {
+		t.Errorf("退避时长不符: %v %v", LoginBackoff(1), LoginBackoff(100))
+	}
+}
+
+// TestLockSecrets 运行中的实例持有口令的共享锁，重新加密需等其结束
+func TestLockSecrets(t *testing.T) {
+	cfg := &Config{Paths: Paths{Profile: DefaultProfile, Dir: t.TempDir()}}
+	g, err := InitGlobalVars(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LockSecrets(cfg.Paths, false); !errors.Is(err, util.ErrLocked) {
+		t.Fatalf("运行中应拒绝重新加密: %v", err)
+	}
+	g.Close()
+	lock, err := LockSecrets(cfg.Paths, false)
+	if err != nil {
+		t.Fatalf("实例结束后应可重新加密: %v", err)
+	}
+	lock.Unlock()
+}
+
+func TestNewConfigSealedValues(t *testing.T) {
+	t.Setenv("TELECOM_DATA_DIR", t.TempDir())
+	t.Setenv("jdhf", "")
+	t.Setenv("MEXZ", "")
+	t.Setenv("CTIME", "")
+	t.Setenv(secret.EnvKey, "test-key")
+	sealed, err := secret.NewBox("test-key").Seal("p#ss&word")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := writeFile(t, "telecom.yaml", `
+accounts:
+  - phone: "13800138000"
+    password: "`+sealed+`"
+  - phone: "13900139000"
+    password: "123456"
+`)
+
+	cfg, err := NewConfig(Options{ConfigFile: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Accounts[0].Password != "p#ss&word" {
+		t.Errorf("未解密密码: %q", cfg.Accounts[0].Password)
+	}
+	want := path + ": accounts[0].password (13800138000)"
+	if got := cfg.SealedValues(); len(got) != 1 || got[0] != want {
+		t.Errorf("SealedValues = %v，期望 [%s]", got, want)
+	}
+}
@@ -0,0 +1,262 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"HighFrequencyTrading/secret"
+	"HighFrequencyTrading/sign"
+	"gopkg.in/yaml.v3"
+)
+
+// 配置值来源
+const (
+	SourceFlag    = "flag"
+	SourceEnv     = "env"
+	SourceFile    = "file"
+	SourceDefault = "default"
+)
+
+// WxpusherFile : wxpusher 推送配置文件名
+const WxpusherFile = "wxpusher.yaml"
+
+// Source : 配置值的来源
+type Source struct {
+	Kind string `json:"kind"`           // flag / env / file / default
+	Name string `json:"name,omitempty"` // 参数名、环境变量名或文件路径
+}
+
+func (s Source) String() string {
+	if s.Name == "" {
+		return s.Kind
+	}
+	return s.Kind + " (" + s.Name + ")"
+}
+
+func envSource(name string) Source  { return Source{Kind: SourceEnv, Name: name} }
+func fileSource(path string) Source { return Source{Kind: SourceFile, Name: path} }
+
+// flagSource : 命令行参数已设置时记录来源，后续环境变量会覆盖
+func (cfg *Config) flagSource(name string, set bool) {
+	if set {
+		cfg.sources[name] = Source{Kind: SourceFlag, Name: "--" + name}
+	}
+}
+
+// Notify : wxpusher 推送配置
+type Notify struct {
+	AppToken string `yaml:"appToken"`
+	UID      string `yaml:"uid"`
+}
+
+// loadNotify : 逐项按 环境变量 → wxpusher.yaml 的顺序确定推送配置，
+// wxpusher.yaml 优先取 profile 目录下的，不存在时取当前目录
+func loadNotify(cfg *Config) error {
+	var file Notify
+	var filePath string
+	for _, path := range []string{filepath.Join(cfg.Paths.Dir, WxpusherFile), WxpusherFile} {
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := yaml.Unmarshal(data, &file); err != nil {
+			return fmt.Errorf("解析 %s 失败: %w", path, err)
+		}
+		filePath = path
+		break
+	}
+
+	pick := func(key, env, fromFile string) string {
+		if v := os.Getenv(env); v != "" {
+			cfg.sources[key] = envSource(env)
+			return v
+		}
+		if fromFile != "" {
+			cfg.sources[key] = fileSource(filePath)
+			return fromFile
+		}
+		return ""
+	}
+	cfg.Notify.AppToken = pick("notify.appToken", "WXPUSHER_APP_TOKEN", file.AppToken)
+	cfg.Notify.UID = pick("notify.uid", "WXPUSHER_UID", file.UID)
+	return nil
+}
+
+// pathSources : profile 与数据目录的来源，规则与 ResolvePaths 一致
+func pathSources(opts Options) (profile, dataDir Source) {
+	profile, dataDir = Source{Kind: SourceDefault}, Source{Kind: SourceDefault, Name: "XDG"}
+	switch {
+	case os.Getenv("TELECOM_PROFILE") != "":
+		profile = envSource("TELECOM_PROFILE")
+	case opts.Profile != "":
+		profile = Source{Kind: SourceFlag, Name: "--profile"}
+	}
+	switch {
+	case os.Getenv("TELECOM_DATA_DIR") != "":
+		dataDir = envSource("TELECOM_DATA_DIR")
+	case opts.DataDir != "":
+		dataDir = Source{Kind: SourceFlag, Name: "--data-dir"}
+	case os.Getenv("TELECOM_HOME") != "":
+		dataDir = envSource("TELECOM_HOME")
+	}
+	return profile, dataDir
+}
+
+// keySource : 加密口令的来源，规则与 secret.Load 一致
+func keySource(paths Paths) Source {
+	if os.Getenv(secret.EnvKey) != "" {
+		return envSource(secret.EnvKey)
+	}
+	path := secret.KeyFilePath(paths.KeyFile())
+	if _, err := os.Stat(path); err == nil {
+		return fileSource(path)
+	}
+	return Source{Kind: SourceDefault}
+}
+
+// Value : 一条合并后的配置及其来源
+type Value struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source Source `json:"source"`
+}
+
+// Mask : 隐藏密码、令牌等敏感值，仅保留首尾少量字符便于核对
+func Mask(v string) string {
+	switch {
+	case v == "":
+		return ""
+	case secret.IsSealed(v):
+		return secret.Prefix + "****"
+	case len(v) <= 8:
+		return "****"
+	default:
+		return v[:4] + "****" + v[len(v)-4:]
+	}
+}
+
+// maskPassword : 密码一律以固定长度的星号显示，不泄露长度
+func maskPassword(v string) string {
+	if v == "" {
+		return ""
+	}
+	return strings.Repeat("*", 6)
+}
+
+// Effective : 列出最终生效的配置及其来源，敏感值已脱敏
+func (cfg *Config) Effective() []Value {
+	var res []Value
+	add := func(key, value string, src Source) {
+		res = append(res, Value{Key: key, Value: value, Source: src})
+	}
+	source := func(key string, fallback Source) Source {
+		if s, ok := cfg.sources[key]; ok {
+			return s
+		}
+		return fallback
+	}
+	def := Source{Kind: SourceDefault}
+
+	add("profile", cfg.Paths.Profile, source("profile", def))
+	add("data-dir", cfg.Paths.Dir, source("data-dir", def))
+	add("config", cfg.ConfigFile, source("config", def))
+	keyState := ""
+	if cfg.Box != nil {
+		keyState = "(configured)"
+	}
+	add("secrets.key", keyState, source("secrets.key", def))
+
+	accSrc := source("accounts", def)
+	for i, acc := range cfg.Accounts {
+		prefix := fmt.Sprintf("accounts[%d]", i)
+		add(prefix+".phone", acc.Phone, accSrc)
+		add(prefix+".auth", acc.AuthMethod(), accSrc)
+		if acc.AuthMethod() == sign.AuthPassword || acc.Password != "" {
+			add(prefix+".password", maskPassword(acc.Password), source(prefix+".password", accSrc))
+		}
+		if acc.Ticket != "" {
+			add(prefix+".ticket", Mask(acc.Ticket), accSrc)
+		}
+		add(prefix+".notifyUid", acc.UID(), accSrc)
+		add(prefix+".enabled", fmt.Sprint(acc.IsEnabled()), accSrc)
+		if len(acc.Items) > 0 {
+			add(prefix+".items", strings.Join(acc.Items, ","), accSrc)
+		}
+		if len(acc.Sessions) > 0 {
+			add(prefix+".sessions", strings.Join(acc.Sessions, ","), accSrc)
+		}
+	}
+
+	add("MEXZ", cfg.MEXZ, source("MEXZ", def))
+	stSrc := source("strategy", def)
+	for i, s := range cfg.Sessions {
+		prefix := fmt.Sprintf("sessions[%d]", i)
+		add(prefix+".name", s.Name, stSrc)
+		add(prefix+".start", s.Start, stSrc)
+		add(prefix+".items", strings.Join(s.Items, ","), stSrc)
+		add(prefix+".burst", s.Plan().String(), stSrc)
+		if s.MaxItems > 0 {
+			add(prefix+".maxItems", fmt.Sprint(s.MaxItems), stSrc)
+		}
+		phones := make([]string, 0, len(s.Overrides))
+		for phone := range s.Overrides {
+			phones = append(phones, phone)
+		}
+		sort.Strings(phones)
+		for _, phone := range phones {
+			o := s.Overrides[phone]
+			op := fmt.Sprintf("%s.overrides[%s]", prefix, phone)
+			if o.Disabled {
+				add(op+".disabled", "true", stSrc)
+			}
+			if len(o.Items) > 0 {
+				add(op+".items", strings.Join(o.Items, ","), stSrc)
+			}
+			if o.MaxItems > 0 {
+				add(op+".maxItems", fmt.Sprint(o.MaxItems), stSrc)
+			}
+		}
+	}
+	if cfg.H != nil {
+		add("trade-hour", fmt.Sprint(*cfg.H), source("trade-hour", def))
+	} else {
+		add("trade-hour", "", def)
+	}
+	add("session", cfg.Session, source("session", def))
+	add("storage", cfg.Storage, source("storage", def))
+	add("cache.ttl", cfg.TicketTTL.String(), source("cache.ttl", def))
+	add("cache.probe", fmt.Sprint(cfg.ProbeTickets), source("cache.probe", def))
+	add("retention.keepMonths", fmt.Sprint(cfg.KeepMonths), source("retention.keepMonths", def))
+	add("retention.pruneMonths", fmt.Sprint(cfg.PruneMonths), source("retention.pruneMonths", def))
+
+	add("login.maxFailures", fmt.Sprint(cfg.MaxLoginFailures), source("login.maxFailures", def))
+	for _, it := range endpointEnvs {
+		add(it.key, *it.field(&cfg.Endpoints), source(it.key, def))
+	}
+	add("protocol.profile", cfg.Protocol.Name, source("protocol.profile", def))
+	add("protocol.file", cfg.protocolFile, source("protocol.file", def))
+	add("catalog.file", cfg.CatalogFile, source("catalog.file", def))
+
+	add("notify.appToken", Mask(cfg.Notify.AppToken), source("notify.appToken", def))
+	add("notify.uid", cfg.Notify.UID, source("notify.uid", def))
+	return res
+}
+
+// Masked : 返回配置文件内容的脱敏副本，用于 config show
+func (fc FileConfig) Masked() FileConfig {
+	accounts := make([]Account, len(fc.Accounts))
+	for i, acc := range fc.Accounts {
+		acc.Password = maskPassword(acc.Password)
+		acc.Ticket = Mask(acc.Ticket)
+		accounts[i] = acc
+	}
+	fc.Accounts = accounts
+	return fc
+}
@@ -0,0 +1,208 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"HighFrequencyTrading/endpoint"
+	"HighFrequencyTrading/sign"
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Account : 单个电信账号及其个性化设置
+type Account struct {
+	Phone     string   `yaml:"phone" toml:"phone" json:"phone"`
+	Password  string   `yaml:"password" toml:"password" json:"password"`
+	NotifyUID string   `yaml:"notifyUid,omitempty" toml:"notifyUid" json:"notifyUid,omitempty"` // wxpusher uid，为空时使用手机号
+	Enabled   *bool    `yaml:"enabled,omitempty" toml:"enabled" json:"enabled,omitempty"`       // 未设置视为启用
+	Items     []string `yaml:"items,omitempty" toml:"items" json:"items,omitempty"`             // 仅兑换这些商品，为空表示不限
+	Sessions  []string `yaml:"sessions,omitempty" toml:"sessions" json:"sessions,omitempty"`    // 仅参与这些场次，为空表示不限
+	Auth      string   `yaml:"auth,omitempty" toml:"auth" json:"auth,omitempty"`                // 登录方式：password（默认）/ sms / ticket
+	Ticket    string   `yaml:"ticket,omitempty" toml:"ticket" json:"ticket,omitempty"`          // auth 为 ticket 时导入的 ticket，可为 enc:v1: 加密值
+}
+
+// AuthMethod : 登录方式，未设置时为服务密码
+func (a Account) AuthMethod() string {
+	if a.Auth == "" {
+		return sign.AuthPassword
+	}
+	return a.Auth
+}
+
+// Authenticator : 按登录方式构造登录器，codes 为短信验证码的来源
+func (a Account) Authenticator(codes sign.CodeSource) (sign.Authenticator, error) {
+	switch a.AuthMethod() {
+	case sign.AuthPassword:
+		return sign.PasswordAuth{Password: a.Password}, nil
+	case sign.AuthSMS:
+		return sign.SMSAuth{Codes: codes}, nil
+	case sign.AuthTicket:
+		return sign.TicketAuth{Ticket: a.Ticket}, nil
+	default:
+		return nil, fmt.Errorf("%s 的登录方式 %q 未知，可选: %s", a.Phone, a.Auth, strings.Join(sign.AuthMethods, " / "))
+	}
+}
+
+// IsEnabled : 账号是否启用
+func (a Account) IsEnabled() bool {
+	return a.Enabled == nil || *a.Enabled
+}
+
+// UID : 推送用的 uid，未配置时退回手机号（与 jdhf 旧格式一致）
+func (a Account) UID() string {
+	if a.NotifyUID != "" {
+		return a.NotifyUID
+	}
+	return a.Phone
+}
+
+// WantsItem : 账号是否需要兑换该商品
+func (a Account) WantsItem(title string) bool {
+	if len(a.Items) == 0 {
+		return true
+	}
+	for _, it := range a.Items {
+		if ItemTitle(it) == title {
+			return true
+		}
+	}
+	return false
+}
+
+// WantsSession : 账号是否参与该场次
+func (a Account) WantsSession(name string) bool {
+	if len(a.Sessions) == 0 {
+		return true
+	}
+	for _, s := range a.Sessions {
+		if s == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Strategy : 兑换策略，对应旧的 MEXZ 字符串
+type Strategy struct {
+	TradeHour *int      `yaml:"tradeHour,omitempty" toml:"tradeHour" json:"tradeHour,omitempty"` // 强制交易时段，按开场小时匹配场次
+	Session   string    `yaml:"session,omitempty" toml:"session" json:"session,omitempty"`       // 强制场次名，优先于 tradeHour
+	Morning   []string  `yaml:"morning,omitempty" toml:"morning" json:"morning,omitempty"`       // 旧写法：10 点场商品，如 ["0.5", "5"]
+	Afternoon []string  `yaml:"afternoon,omitempty" toml:"afternoon" json:"afternoon,omitempty"` // 旧写法：14 点场商品，如 ["1", "10"]
+	Sessions  []Session `yaml:"sessions,omitempty" toml:"sessions" json:"sessions,omitempty"`    // 自定义场次
+	Burst     *Burst    `yaml:"burst,omitempty" toml:"burst" json:"burst,omitempty"`             // 开场时每个商品的尝试计划，场次可单独覆盖
+}
+
+// FileConfig : 配置文件（YAML/TOML）对应的结构体
+type FileConfig struct {
+	Accounts  []Account           `yaml:"accounts" toml:"accounts"`
+	Strategy  *Strategy           `yaml:"strategy,omitempty" toml:"strategy"`
+	Storage   string              `yaml:"storage,omitempty" toml:"storage"` // 存储后端：json（默认）/ sqlite
+	Cache     *CacheConfig        `yaml:"cache,omitempty" toml:"cache"`
+	Retention *Retention          `yaml:"retention,omitempty" toml:"retention"`
+	Login     *LoginConfig        `yaml:"login,omitempty" toml:"login"`
+	Endpoints *endpoint.Endpoints `yaml:"endpoints,omitempty" toml:"endpoints"` // 接口地址，用于预发环境或本地桩服务
+	Protocol  *ProtocolConfig     `yaml:"protocol,omitempty" toml:"protocol"`   // 客户端协议常量
+	Catalog   *CatalogConfig      `yaml:"catalog,omitempty" toml:"catalog"`     // 商品目录
+}
+
+// CatalogConfig : 商品目录
+type CatalogConfig struct {
+	File string `yaml:"file,omitempty" toml:"file"` // 商城拉取失败时使用的目录文件，格式见 catalog.example.yaml
+}
+
+// ProtocolConfig : 客户端协议 profile 的选择
+type ProtocolConfig struct {
+	Profile string `yaml:"profile,omitempty" toml:"profile"` // profile 名称，为空时使用默认 profile
+	File    string `yaml:"file,omitempty" toml:"file"`       // 覆盖或新增 profile 的文件，格式同内置的 protocols.yaml
+}
+
+// LoginConfig : 登录失败保护
+type LoginConfig struct {
+	MaxFailures *int `yaml:"maxFailures,omitempty" toml:"maxFailures"` // 连续凭证错误达到该次数后暂停自动登录，0 表示不暂停
+}
+
+// Retention : 兑换记录保留策略，月份均包含当月，0 表示不启用
+type Retention struct {
+	KeepMonths  *int `yaml:"keepMonths,omitempty" toml:"keepMonths"`   // 账本中保留最近几个月，更早的按月归档
+	PruneMonths *int `yaml:"pruneMonths,omitempty" toml:"pruneMonths"` // 最多保留最近几个月（含归档），更早的删除
+}
+
+// CacheConfig : ticket 缓存策略
+type CacheConfig struct {
+	TTL   string `yaml:"ttl,omitempty" toml:"ttl"`     // 签发后多久视为过期，如 "12h"；"0" 表示不按时间过期
+	Probe *bool  `yaml:"probe,omitempty" toml:"probe"` // 开场前是否校验缓存的 ticket，默认 true
+}
+
+// LoadFile : 读取配置文件，按扩展名选择 TOML 或 YAML
+func LoadFile(path string) (*FileConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	fc := &FileConfig{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		md, err := toml.Decode(string(data), fc)
+		if err != nil {
+			return nil, fmt.Errorf("解析 %s 失败: %w", path, err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return nil, fmt.Errorf("解析 %s 失败: 未知字段 %v", path, undecoded)
+		}
+	default:
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(fc); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("解析 %s 失败: %w", path, err)
+		}
+	}
+	return fc, nil
+}
+
+// ParseJdhf : 解析旧格式 phone#password#uid&phone2#pwd2#uid2，
+// 格式错误的条目会被跳过并体现在返回的 error 中
+func ParseJdhf(raw string) ([]Account, error) {
+	var accounts []Account
+	var errs []error
+	for i, entry := range strings.Split(raw, "&") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		fields := strings.Split(entry, "#")
+		if len(fields) < 2 {
+			errs = append(errs, fmt.Errorf("jdhf[%d]: 账号格式错误，应为 phone#password#uid", i))
+			continue
+		}
+		acc := Account{Phone: fields[0], Password: fields[1]}
+		if len(fields) >= 3 {
+			acc.NotifyUID = fields[len(fields)-1]
+		}
+		accounts = append(accounts, acc)
+	}
+	return accounts, errors.Join(errs...)
+}
+
+func splitItems(raw string) []string {
+	var res []string
+	for _, it := range strings.Split(raw, ",") {
+		if it = strings.TrimSpace(it); it != "" {
+			res = append(res, it)
+		}
+	}
+	return res
+}
+
+// ItemTitle : 将 "5" 规范为 "5元话费"，已是完整标题则原样返回
+func ItemTitle(raw string) string {
+	if strings.HasSuffix(raw, "元话费") {
+		return raw
+	}
+	return raw + "元话费"
+}
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"HighFrequencyTrading/secret"
+	"HighFrequencyTrading/sign"
+	"HighFrequencyTrading/store"
+)
+
+const (
+	DefaultMaxLoginFailures = 3               // 连续凭证错误达到该次数后暂停自动登录
+	LoginBackoffBase        = 5 * time.Minute // 首次失败后的等待时长，之后每次翻倍
+	LoginBackoffMax         = 24 * time.Hour  // 退避的上限
+)
+
+// credential : 参与摘要的凭证内容，任一项变化即视为配置已修改
+func (a Account) credential() string {
+	return a.AuthMethod() + "\x00" + a.Password + "\x00" + a.Ticket
+}
+
+// fingerprintKey : 凭证摘要的密钥，保存在 profile 目录中，与加密口令无关
+func (g *GlobalVars) fingerprintKey() ([]byte, error) {
+	g.fpOnce.Do(func() {
+		g.fpKey, g.fpErr = secret.LoadFingerprintKey(g.Paths.FingerprintKey())
+	})
+	return g.fpKey, g.fpErr
+}
+
+// LoginBackoff : 连续失败 failures 次后的等待时长
+func LoginBackoff(failures int) time.Duration {
+	d := LoginBackoffBase
+	for i := 1; i < failures && d < LoginBackoffMax; i++ {
+		d *= 2
+	}
+	if d > LoginBackoffMax {
+		d = LoginBackoffMax
+	}
+	return d
+}
+
+// LoginBlocked : 账号当前是否不应自动登录，返回原因；允许时返回空串。
+// 记录中的凭证摘要与当前配置不一致时视为配置已修改，清除记录并允许登录
+func (g *GlobalVars) LoginBlocked(acc Account, now time.Time) (string, error) {
+	if g.Store == nil {
+		return "", nil
+	}
+	states, err := g.Store.LoginStates()
+	if err != nil {
+		return "", err
+	}
+	st, ok := states[acc.Phone]
+	if !ok {
+		return "", nil
+	}
+	if st.Fingerprint != "" {
+		key, err := g.fingerprintKey()
+		if err != nil {
+			return "", err
+		}
+		if !secret.MatchFingerprint(key, st.Fingerprint, acc.credential()) {
+			return "", g.Store.DeleteLoginState(acc.Phone)
+		}
+	}
+	if st.Suspended() {
+		return fmt.Sprintf("已于 %s 暂停自动登录（%s），修改配置中的凭证后自动恢复",
+			st.SuspendedAt.Format("2006-01-02 15:04:05"), st.LastError), nil
+	}
+	if now.Before(st.NextAttempt) {
+		return fmt.Sprintf("已连续登录失败 %d 次（%s），%s 前不再尝试",
+			st.Failures, st.LastError, st.NextAttempt.Format("2006-01-02 15:04:05")), nil
+	}
+	return "", nil
+}
+
+// RecordLoginFailure : 记录一次登录失败并计算退避；服务暂不可用与未发出请求的本地凭证、输入错误不计入。
+// 连续凭证错误达到 g.MaxLoginFailures 次（大于 0 时）或账号已被锁定时暂停自动登录，
+// suspended 表示本次失败导致了暂停
+func (g *GlobalVars) RecordLoginFailure(acc Account, loginErr error, now time.Time) (st store.LoginState, suspended bool, err error) {
+	if g.Store == nil || sign.Retryable(loginErr) || errors.Is(loginErr, sign.ErrInvalidCredential) || errors.Is(loginErr, sign.ErrInvalidInput) {
+		return st, false, nil
+	}
+	states, err := g.Store.LoginStates()
+	if err != nil {
+		return st, false, err
+	}
+	st = states[acc.Phone]
+	key, err := g.fingerprintKey()
+	if err != nil {
+		return st, false, err
+	}
+	if !secret.MatchFingerprint(key, st.Fingerprint, acc.credential()) {
+		// 凭证已修改，此前的失败不再累计
+		if st.Fingerprint, err = secret.Fingerprint(key, acc.credential()); err != nil {
+			return store.LoginState{}, false, err
+		}
+		st = store.LoginState{Fingerprint: st.Fingerprint}
+	}
+
+	st.Failures++
+	if errors.Is(loginErr, sign.ErrWrongPassword) || errors.Is(loginErr, sign.ErrAccountLocked) {
+		st.CredentialFailures++
+	} else {
+		st.CredentialFailures = 0
+	}
+	st.LastError = loginErr.Error()
+	st.LastFailure = now
+	st.NextAttempt = now.Add(LoginBackoff(st.Failures))
+	if !st.Suspended() && (errors.Is(loginErr, sign.ErrAccountLocked) ||
+		(g.MaxLoginFailures > 0 && st.CredentialFailures >= g.MaxLoginFailures)) {
+		st.SuspendedAt = now
+		suspended = true
+	}
+	return st, suspended, g.Store.PutLoginState(acc.Phone, st)
+}
+
+// RecordLoginSuccess : 登录成功，清除失败记录
+func (g *GlobalVars) RecordLoginSuccess(phone string) error {
+	if g.Store == nil {
+		return nil
+	}
+	states, err := g.Store.LoginStates()
+	if err != nil {
+		return err
+	}
+	if _, ok := states[phone]; !ok {
+		return nil
+	}
+	return g.Store.DeleteLoginState(phone)
+}
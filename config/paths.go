@@ -0,0 +1,221 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"HighFrequencyTrading/store"
+)
+
+// DefaultProfile : 未指定 profile 时使用的名称
+const DefaultProfile = "default"
+
+var profilePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// Paths : 单个 profile 的数据目录，配置、兑换记录、缓存与日志均存放于此
+type Paths struct {
+	Profile string
+	Dir     string // 为空时退回当前工作目录（旧行为）
+}
+
+func (p Paths) file(name string) string { return filepath.Join(p.Dir, name) }
+
+// ExchangeLog : 兑换记录（年月 -> 标题 -> 手机号）
+func (p Paths) ExchangeLog() string { return p.file(ExchangeLogFile) }
+
+// ExchangeLog2 : 按手机号整理的兑换记录
+func (p Paths) ExchangeLog2() string { return p.file(ExchangeLogFile2) }
+
+// Ledger : 逐次兑换尝试的 JSONL 账本
+func (p Paths) Ledger() string { return p.file(LedgerFile) }
+
+// ArchiveDir : 按月归档的兑换记录
+func (p Paths) ArchiveDir() string { return p.file(store.ArchiveDir) }
+
+// Cache : ticket 缓存
+func (p Paths) Cache() string { return p.file(CacheFile) }
+
+// Catalog : 当天商品目录的缓存
+func (p Paths) Catalog() string { return p.file(CatalogFile) }
+
+// KeyFile : 加密口令文件
+func (p Paths) KeyFile() string { return p.file(KeyFile) }
+
+// FingerprintKey : 凭证摘要的密钥文件
+func (p Paths) FingerprintKey() string { return p.file(FingerprintFile) }
+
+// Vault : 加密保存的账号密码
+func (p Paths) Vault() string { return p.file(VaultFile) }
+
+// LogDir : 运行日志目录
+func (p Paths) LogDir() string { return p.file("logs") }
+
+// ConfigFile : profile 自带的配置文件，依次查找 config.yaml / config.yml / config.toml
+func (p Paths) ConfigFile() (string, bool) {
+	for _, name := range []string{"config.yaml", "config.yml", "config.toml"} {
+		path := p.file(name)
+		if _, err := os.Stat(path); err == nil {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// DataRoot : 所有 profile 的根目录，遵循 XDG 规范：
+// $TELECOM_HOME → $XDG_DATA_HOME/telecom → ~/.local/share/telecom
+func DataRoot() (string, error) {
+	if home := os.Getenv("TELECOM_HOME"); home != "" {
+		return home, nil
+	}
+	if xdg := os.Getenv("XDG_DATA_HOME"); xdg != "" {
+		return filepath.Join(xdg, "telecom"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("无法确定数据目录，请使用 --data-dir: %w", err)
+	}
+	return filepath.Join(home, ".local", "share", "telecom"), nil
+}
+
+// ResolvePaths : 确定数据目录，优先级：TELECOM_DATA_DIR → --data-dir → TELECOM_PROFILE / --profile 对应的目录
+func ResolvePaths(opts Options) (Paths, error) {
+	profile := opts.Profile
+	if env := os.Getenv("TELECOM_PROFILE"); env != "" {
+		profile = env
+	}
+	dataDir := opts.DataDir
+	if env := os.Getenv("TELECOM_DATA_DIR"); env != "" {
+		dataDir = env
+	}
+
+	if dataDir != "" {
+		if profile == "" {
+			profile = filepath.Base(dataDir)
+		}
+		return Paths{Profile: profile, Dir: dataDir}, nil
+	}
+
+	if profile == "" {
+		profile = DefaultProfile
+	}
+	if !profilePattern.MatchString(profile) {
+		return Paths{}, fmt.Errorf("profile 名称 %q 只能包含字母、数字、'.'、'_' 与 '-'", profile)
+	}
+	root, err := DataRoot()
+	if err != nil {
+		return Paths{}, err
+	}
+	return Paths{Profile: profile, Dir: filepath.Join(root, profile)}, nil
+}
+
+// LegacyDirs : 除数据目录外还需查找旧版兑换日志与缓存的目录，即旧版本写入数据的工作目录。
+// 只有默认 profile 继承工作目录中的旧数据，其它 profile 需用 store migrate --from 显式导入，
+// 避免新建的 profile 带上另一组账号的 ticket 与兑换记录
+func (p Paths) LegacyDirs() []string {
+	if p.Profile != DefaultProfile {
+		return nil
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil
+	}
+	if dir, err := filepath.Abs(p.Dir); err == nil && dir == cwd {
+		return nil
+	}
+	return []string{cwd}
+}
+
+// Ensure : 创建数据目录；首次创建默认 profile 时复制当前目录下的密钥文件与保险库。
+// 旧版兑换日志与缓存由 OpenStore 按存储的 legacy_imported 标记导入，与 profile 无关
+func (p Paths) Ensure() error {
+	if p.Dir == "" {
+		return nil
+	}
+	_, statErr := os.Stat(p.Dir)
+	if err := os.MkdirAll(p.Dir, 0700); err != nil {
+		return err
+	}
+	if errors.Is(statErr, os.ErrNotExist) && p.Profile == DefaultProfile {
+		importLegacyFiles(p)
+	}
+	return nil
+}
+
+// importLegacyFiles : 旧版本把密钥与保险库写在工作目录，升级后复制到默认 profile，避免无法解密
+func importLegacyFiles(p Paths) {
+	for _, name := range []string{KeyFile, VaultFile} {
+		src, err := os.Open(name)
+		if err != nil {
+			continue
+		}
+		dst, err := os.OpenFile(p.file(name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err == nil {
+			_, err = io.Copy(dst, src)
+			dst.Close()
+		}
+		src.Close()
+		if err != nil {
+			log.Printf("[Profile] 导入旧文件 %s 失败: %v", name, err)
+			continue
+		}
+		log.Printf("[Profile] 已将当前目录下的 %s 导入 %s", name, p.Dir)
+	}
+}
+
+// OpenLogFile : 打开当天的运行日志文件（追加写）
+func (p Paths) OpenLogFile() (*os.File, error) {
+	if err := os.MkdirAll(p.LogDir(), 0700); err != nil {
+		return nil, err
+	}
+	name := fmt.Sprintf("telecom-%s.log", time.Now().Format("20060102"))
+	return os.OpenFile(filepath.Join(p.LogDir(), name), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+}
+
+// ProfileInfo : profiles list 展示的信息
+type ProfileInfo struct {
+	Name      string
+	Dir       string
+	HasConfig bool
+	Modified  time.Time // 目录内文件的最近修改时间
+}
+
+// ListProfiles : 列出数据根目录下的所有 profile
+func ListProfiles() ([]ProfileInfo, error) {
+	root, err := DataRoot()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var res []ProfileInfo
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		p := Paths{Profile: e.Name(), Dir: filepath.Join(root, e.Name())}
+		info := ProfileInfo{Name: p.Profile, Dir: p.Dir}
+		_, info.HasConfig = p.ConfigFile()
+		files, _ := os.ReadDir(p.Dir)
+		for _, f := range files {
+			if fi, err := f.Info(); err == nil && fi.ModTime().After(info.Modified) {
+				info.Modified = fi.ModTime()
+			}
+		}
+		res = append(res, info)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res, nil
+}
@@ -0,0 +1,84 @@
+// paths_test.go
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolvePaths(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("TELECOM_HOME", home)
+	t.Setenv("TELECOM_PROFILE", "")
+	t.Setenv("TELECOM_DATA_DIR", "")
+
+	p, err := ResolvePaths(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Profile != DefaultProfile || p.Dir != filepath.Join(home, DefaultProfile) {
+		t.Errorf("默认 profile 不符: %+v", p)
+	}
+
+	p, _ = ResolvePaths(Options{Profile: "family"})
+	if p.Cache() != filepath.Join(home, "family", CacheFile) {
+		t.Errorf("profile 缓存路径不符: %s", p.Cache())
+	}
+
+	// 环境变量优先于命令行
+	t.Setenv("TELECOM_PROFILE", "work")
+	p, _ = ResolvePaths(Options{Profile: "family"})
+	if p.Profile != "work" {
+		t.Errorf("TELECOM_PROFILE 未生效: %+v", p)
+	}
+
+	dir := t.TempDir()
+	p, _ = ResolvePaths(Options{DataDir: dir})
+	if p.Dir != dir || p.ExchangeLog() != filepath.Join(dir, ExchangeLogFile) {
+		t.Errorf("--data-dir 未生效: %+v", p)
+	}
+
+	t.Setenv("TELECOM_PROFILE", "")
+	if _, err := ResolvePaths(Options{Profile: "../etc"}); err == nil {
+		t.Error("非法 profile 名称应报错")
+	}
+}
+
+func TestProfileConfigAndList(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("TELECOM_HOME", home)
+	t.Setenv("TELECOM_PROFILE", "")
+	t.Setenv("TELECOM_DATA_DIR", "")
+	t.Setenv("TELECOM_CONFIG", "")
+	t.Setenv("jdhf", "")
+	t.Setenv("MEXZ", "")
+
+	p, _ := ResolvePaths(Options{Profile: "family"})
+	if err := p.Ensure(); err != nil {
+		t.Fatal(err)
+	}
+	cfgPath := filepath.Join(p.Dir, "config.yaml")
+	if err := os.WriteFile(cfgPath, []byte("accounts:\n  - phone: \"13800138000\"\n    password: \"123456\"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := NewConfig(Options{Profile: "family"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ConfigFile != cfgPath || len(cfg.Accounts) != 1 {
+		t.Errorf("未使用 profile 自带的配置文件: %s", cfg.ConfigFile)
+	}
+
+	if _, err := NewConfig(Options{Profile: "other"}); err != nil {
+		t.Fatal(err)
+	}
+	profiles, err := ListProfiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(profiles) != 2 || profiles[0].Name != "family" || !profiles[0].HasConfig || profiles[1].HasConfig {
+		t.Errorf("profile 列表不符: %+v", profiles)
+	}
+}
@@ -0,0 +1,108 @@
+package config
+
+import (
+	"fmt"
+	"log"
+
+	"HighFrequencyTrading/secret"
+	"HighFrequencyTrading/sign"
+	"HighFrequencyTrading/store"
+)
+
+// resolvePasswords : 解密 enc:v1: 形式的密码与 ticket，密码登录且未填写密码的账号从保险库读取
+func resolvePasswords(cfg *Config) error {
+	var vault *secret.Vault
+	for i := range cfg.Accounts {
+		acc := &cfg.Accounts[i]
+		loc := fmt.Sprintf("%s: accounts[%d]", cfg.accountsFrom, i)
+		if secret.IsSealed(acc.Ticket) {
+			cfg.sealed = append(cfg.sealed, fmt.Sprintf("%s.ticket (%s)", loc, acc.Phone))
+		}
+		if secret.IsSealed(acc.Password) {
+			cfg.sealed = append(cfg.sealed, fmt.Sprintf("%s.password (%s)", loc, acc.Phone))
+		}
+		if acc.Ticket != "" {
+			ticket, err := secret.Reveal(cfg.Box, acc.Ticket)
+			if err != nil {
+				return fmt.Errorf("解密 %s 的 ticket 失败: %w", acc.Phone, err)
+			}
+			acc.Ticket = ticket
+		}
+		if acc.Password == "" && acc.AuthMethod() == sign.AuthPassword {
+			if vault == nil {
+				v, err := secret.LoadVault(cfg.Paths.Vault())
+				if err != nil {
+					return err
+				}
+				vault = v
+			}
+			pwd, ok, err := vault.Password(cfg.Box, acc.Phone)
+			if err != nil {
+				return fmt.Errorf("读取 %s 的密码失败: %w", acc.Phone, err)
+			}
+			if ok {
+				acc.Password = pwd
+				cfg.sources[fmt.Sprintf("accounts[%d].password", i)] = fileSource(cfg.Paths.Vault())
+			}
+			continue
+		}
+		if acc.Password == "" {
+			continue
+		}
+		pwd, err := secret.Reveal(cfg.Box, acc.Password)
+		if err != nil {
+			return fmt.Errorf("解密 %s 的密码失败: %w", acc.Phone, err)
+		}
+		acc.Password = pwd
+	}
+	return nil
+}
+
+// SealedValues : 配置中以 enc:v1: 形式填写的密码与 ticket 的位置。
+// 这些值只能由当前密钥解密，轮换密钥时不会被改写
+func (cfg *Config) SealedValues() []string {
+	return cfg.sealed
+}
+
+// openCache : 解密缓存中的 ticket，无法解密的条目丢弃后重新登录
+func openCache(box *secret.Box, c map[string]store.Ticket) map[string]store.Ticket {
+	res := make(map[string]store.Ticket, len(c))
+	for phone, t := range c {
+		plain, err := secret.Reveal(box, t.Ticket)
+		if err != nil {
+			log.Printf("[Cache] phone=%s 缓存无法解密，将重新登录: %v", phone, err)
+			continue
+		}
+		t.Ticket = plain
+		res[phone] = t
+	}
+	return res
+}
+
+// sealTicket : 配置了口令时加密 ticket，否则原样返回
+func sealTicket(box *secret.Box, ticket string) (string, error) {
+	if box == nil {
+		return ticket, nil
+	}
+	return box.Seal(ticket)
+}
+
+// ResealCache : 用 newBox 重新加密存储中的缓存，oldBox 用于解密已有的加密条目；
+// 只返回重新加密后的条目，不写回存储，由调用方确认其它数据也处理成功后再写入
+func ResealCache(st store.Store, oldBox, newBox *secret.Box) (map[string]store.Ticket, error) {
+	c, err := st.Cache()
+	if err != nil {
+		return nil, err
+	}
+	for phone, t := range c {
+		plain, err := secret.Reveal(oldBox, t.Ticket)
+		if err != nil {
+			return nil, fmt.Errorf("解密 %s 的缓存失败: %w", phone, err)
+		}
+		if t.Ticket, err = sealTicket(newBox, plain); err != nil {
+			return nil, err
+		}
+		c[phone] = t
+	}
+	return c, nil
+}
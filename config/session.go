@@ -0,0 +1,215 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	SessionGrace     = 30 * time.Minute // 开场后仍可参与的时长，超过则放弃本场
+	SessionLookahead = time.Hour        // 跨天场次（如午夜场）最多提前多久启动
+)
+
+// Session : 一个兑换场次，如 10 点场、14 点场或临时加开的午夜场
+type Session struct {
+	Name      string                     `yaml:"name" toml:"name" json:"name"`
+	Start     string                     `yaml:"start" toml:"start" json:"start"` // 开场时间 HH:MM:SS，秒与分可省略
+	Items     []string                   `yaml:"items" toml:"items" json:"items"`
+	Overrides map[string]SessionOverride `yaml:"overrides,omitempty" toml:"overrides" json:"overrides,omitempty"` // 手机号 -> 单账号覆盖
+	Burst     *Burst                     `yaml:"burst,omitempty" toml:"burst" json:"burst,omitempty"`             // 开场时的尝试计划，覆盖 strategy.burst
+	MaxItems  int                        `yaml:"maxItems,omitempty" toml:"maxItems" json:"maxItems,omitempty"`    // 大于 0 时 items 视为偏好顺序：依次兑换，未兑到时退到下一个，最多兑到 maxItems 个
+}
+
+// SessionOverride : 场次内针对单个账号的覆盖设置
+type SessionOverride struct {
+	Items    []string `yaml:"items,omitempty" toml:"items" json:"items,omitempty"` // 替换场次商品
+	Disabled bool     `yaml:"disabled,omitempty" toml:"disabled" json:"disabled,omitempty"`
+	MaxItems int      `yaml:"maxItems,omitempty" toml:"maxItems" json:"maxItems,omitempty"` // 替换场次的 maxItems
+}
+
+// legacySessions : 旧版 MEXZ 两段分别对应的场次
+var legacySessions = []struct{ Name, Start string }{
+	{"10", "10:00:00"},
+	{"14", "14:00:00"},
+}
+
+// ParseClock : 解析 HH[:MM[:SS]]
+func ParseClock(raw string) (h, m, s int, err error) {
+	parts := strings.Split(strings.TrimSpace(raw), ":")
+	if len(parts) > 3 || parts[0] == "" {
+		return 0, 0, 0, fmt.Errorf("开场时间 %q 格式应为 HH:MM:SS", raw)
+	}
+	vals := make([]int, 3)
+	limits := []int{23, 59, 59}
+	for i, p := range parts {
+		if _, err := fmt.Sscanf(p, "%d", &vals[i]); err != nil || vals[i] < 0 || vals[i] > limits[i] {
+			return 0, 0, 0, fmt.Errorf("开场时间 %q 格式应为 HH:MM:SS", raw)
+		}
+	}
+	return vals[0], vals[1], vals[2], nil
+}
+
+// Clock : 场次开场的时、分、秒
+func (s Session) Clock() (h, m, sec int, err error) {
+	return ParseClock(s.Start)
+}
+
+// At : 场次在 day 当天的开场时间
+func (s Session) At(day time.Time) time.Time {
+	h, m, sec, _ := s.Clock()
+	return time.Date(day.Year(), day.Month(), day.Day(), h, m, sec, 0, day.Location())
+}
+
+// Next : 相对 now 最近一次需要参与的开场时间：今天开场未超过 SessionGrace 取今天，
+// 否则明天的场次在 SessionLookahead 内开场时取明天；ok=false 表示今天已结束且明天尚远
+func (s Session) Next(now time.Time) (t time.Time, ok bool) {
+	today := s.At(now)
+	if now.Before(today.Add(SessionGrace)) {
+		return today, true
+	}
+	tomorrow := s.At(now.AddDate(0, 0, 1))
+	if tomorrow.Sub(now) <= SessionLookahead {
+		return tomorrow, true
+	}
+	return today, false
+}
+
+// Titles : 场次商品标题
+func (s Session) Titles() []string {
+	return parseExchanges(s.Items)
+}
+
+// ItemsFor : 指定账号在该场次要兑换的商品标题；ok=false 表示该账号不参与
+func (s Session) ItemsFor(phone string) (titles []string, ok bool) {
+	if o, found := s.Overrides[phone]; found {
+		if o.Disabled {
+			return nil, false
+		}
+		if len(o.Items) > 0 {
+			return parseExchanges(o.Items), true
+		}
+	}
+	return s.Titles(), true
+}
+
+// MaxItemsFor : 指定账号在该场次最多兑到的商品数，0 表示不限（所有商品同时兑换）
+func (s Session) MaxItemsFor(phone string) int {
+	if o, found := s.Overrides[phone]; found && o.MaxItems > 0 {
+		return o.MaxItems
+	}
+	return s.MaxItems
+}
+
+// AllTitles : 场次及其所有账号覆盖中出现的商品标题
+func (s Session) AllTitles() []string {
+	titles := s.Titles()
+	for _, o := range s.Overrides {
+		for _, t := range parseExchanges(o.Items) {
+			if !containsString(titles, t) {
+				titles = append(titles, t)
+			}
+		}
+	}
+	return titles
+}
+
+func containsString(arr []string, s string) bool {
+	for _, v := range arr {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// SessionList : 合并旧写法 morning/afternoon 与自定义 sessions，并将 strategy.burst 合并到每个场次
+func (st Strategy) SessionList() []Session {
+	var res []Session
+	if len(st.Morning) > 0 {
+		res = append(res, Session{Name: legacySessions[0].Name, Start: legacySessions[0].Start, Items: st.Morning})
+	}
+	if len(st.Afternoon) > 0 {
+		res = append(res, Session{Name: legacySessions[1].Name, Start: legacySessions[1].Start, Items: st.Afternoon})
+	}
+	res = append(res, st.Sessions...)
+	for i := range res {
+		res[i].Burst = st.Burst.merge(res[i].Burst)
+	}
+	return res
+}
+
+// FindSession : 按名称查找场次
+func FindSession(sessions []Session, name string) (Session, bool) {
+	for _, s := range sessions {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return Session{}, false
+}
+
+// ParseMEXZ : 解析兑换策略，场次之间以 ';' 分隔，支持两种写法：
+//   - 旧写法 "0.5,5;1,10"：恰好两段，分别对应 10 点场与 14 点场
+//   - 扩展写法 "[name@]HH:MM:SS=0.5,5"：每段显式给出开场时间，name 缺省为开场时间
+func ParseMEXZ(raw string) (Strategy, error) {
+	parts := strings.Split(raw, ";")
+	if !strings.Contains(raw, "=") {
+		if len(parts) != len(legacySessions) {
+			return Strategy{}, fmt.Errorf("MEXZ 应以 ';' 分为两场，或使用 HH:MM:SS=商品 写法，实际 %d 段", len(parts))
+		}
+		var st Strategy
+		for i, part := range parts {
+			st.Sessions = append(st.Sessions, Session{
+				Name:  legacySessions[i].Name,
+				Start: legacySessions[i].Start,
+				Items: splitItems(part),
+			})
+		}
+		return st, nil
+	}
+
+	var st Strategy
+	for i, part := range parts {
+		head, items, found := strings.Cut(part, "=")
+		if !found {
+			return Strategy{}, fmt.Errorf("MEXZ 第 %d 段 %q 缺少开场时间，应为 [name@]HH:MM:SS=商品", i, part)
+		}
+		name, start, named := strings.Cut(head, "@")
+		if !named {
+			start, name = name, ""
+		}
+		start = strings.TrimSpace(start)
+		if _, _, _, err := ParseClock(start); err != nil {
+			return Strategy{}, fmt.Errorf("MEXZ 第 %d 段: %w", i, err)
+		}
+		if name = strings.TrimSpace(name); name == "" {
+			name = start
+		}
+		st.Sessions = append(st.Sessions, Session{Name: name, Start: start, Items: splitItems(items)})
+	}
+	return st, nil
+}
+
+// MEXZ : 将策略还原为 MEXZ 字符串，便于日志展示
+func (st Strategy) MEXZ() string {
+	sessions := st.SessionList()
+	legacy := len(sessions) == len(legacySessions)
+	for i := 0; legacy && i < len(sessions); i++ {
+		legacy = sessions[i].Name == legacySessions[i].Name && sessions[i].Start == legacySessions[i].Start
+	}
+
+	var parts []string
+	for _, s := range sessions {
+		items := strings.Join(s.Items, ",")
+		switch {
+		case legacy:
+			parts = append(parts, items)
+		case s.Name == s.Start:
+			parts = append(parts, s.Start+"="+items)
+		default:
+			parts = append(parts, s.Name+"@"+s.Start+"="+items)
+		}
+	}
+	return strings.Join(parts, ";")
+}
@@ -0,0 +1,145 @@
+// session_test.go
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseMEXZ(t *testing.T) {
+	st, err := ParseMEXZ("0.5,5;1,10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(st.Sessions) != 2 || st.Sessions[0].Name != "10" || st.Sessions[1].Start != "14:00:00" {
+		t.Fatalf("旧写法解析不符: %+v", st.Sessions)
+	}
+	if st.MEXZ() != "0.5,5;1,10" {
+		t.Errorf("旧写法应原样还原，实际 %s", st.MEXZ())
+	}
+
+	st, err = ParseMEXZ("midnight@0:00=1,5;10:00:00=0.5;14:30=10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Session{
+		{Name: "midnight", Start: "0:00", Items: []string{"1", "5"}},
+		{Name: "10:00:00", Start: "10:00:00", Items: []string{"0.5"}},
+		{Name: "14:30", Start: "14:30", Items: []string{"10"}},
+	}
+	if len(st.Sessions) != len(want) {
+		t.Fatalf("预期 %d 个场次，实际 %+v", len(want), st.Sessions)
+	}
+	for i, s := range want {
+		got := st.Sessions[i]
+		if got.Name != s.Name || got.Start != s.Start || len(got.Items) != len(s.Items) {
+			t.Errorf("场次 %d 预期 %+v，实际 %+v", i, s, got)
+		}
+	}
+
+	for _, bad := range []string{"0.5;1;10", "25:00=1", "10=1;5"} {
+		if _, err := ParseMEXZ(bad); err == nil {
+			t.Errorf("MEXZ=%q 应解析失败", bad)
+		}
+	}
+}
+
+func TestSessionNext(t *testing.T) {
+	loc := time.Local
+	day := time.Date(2025, 3, 1, 0, 0, 0, 0, loc)
+	at := func(h, m int) time.Time { return day.Add(time.Duration(h)*time.Hour + time.Duration(m)*time.Minute) }
+
+	tests := []struct {
+		session Session
+		now     time.Time
+		want    time.Time
+		ok      bool
+	}{
+		{Session{Start: "10:00:00"}, at(9, 0), at(10, 0), true},
+		{Session{Start: "10:00:00"}, at(10, 20), at(10, 0), true},
+		{Session{Start: "10:00:00"}, at(11, 0), at(10, 0), false},
+		{Session{Start: "0:00"}, at(23, 50), at(24, 0), true},
+		{Session{Start: "14:30:15"}, at(14, 0), at(14, 30).Add(15 * time.Second), true},
+	}
+	for _, tt := range tests {
+		got, ok := tt.session.Next(tt.now)
+		if !got.Equal(tt.want) || ok != tt.ok {
+			t.Errorf("%s @ %v: 预期 %v/%v，实际 %v/%v", tt.session.Start, tt.now, tt.want, tt.ok, got, ok)
+		}
+	}
+}
+
+func TestSessionItemsFor(t *testing.T) {
+	s := Session{
+		Name:  "10",
+		Items: []string{"0.5", "5"},
+		Overrides: map[string]SessionOverride{
+			"13800138000": {Items: []string{"10"}},
+			"13900139000": {Disabled: true},
+		},
+	}
+	if titles, ok := s.ItemsFor("13700137000"); !ok || len(titles) != 2 {
+		t.Errorf("未覆盖账号应使用场次商品: %v", titles)
+	}
+	if titles, ok := s.ItemsFor("13800138000"); !ok || len(titles) != 1 || titles[0] != "10元话费" {
+		t.Errorf("覆盖商品不符: %v", titles)
+	}
+	if _, ok := s.ItemsFor("13900139000"); ok {
+		t.Error("停用账号不应参与")
+	}
+	if got := s.AllTitles(); len(got) != 3 {
+		t.Errorf("AllTitles 应包含覆盖商品: %v", got)
+	}
+}
+
+func TestSessionBurst(t *testing.T) {
+	st := Strategy{
+		Morning: []string{"5"},
+		Burst:   &Burst{Attempts: 3, Interval: "150ms"},
+		Sessions: []Session{
+			{Name: "night", Start: "22:00:00", Items: []string{"1"}, Burst: &Burst{Attempts: 5, Lead: "100ms"}},
+		},
+	}
+	sessions := st.SessionList()
+	if got := sessions[0].Plan(); got != (AttemptPlan{Attempts: 3, Interval: 150 * time.Millisecond}) {
+		t.Errorf("旧写法场次应使用 strategy.burst: %+v", got)
+	}
+	plan := sessions[1].Plan()
+	if plan != (AttemptPlan{Attempts: 5, Interval: 150 * time.Millisecond, Lead: 100 * time.Millisecond}) {
+		t.Errorf("场次 burst 应逐项覆盖: %+v", plan)
+	}
+	t0 := time.Date(2025, 3, 1, 22, 0, 0, 0, time.Local)
+	times := plan.Times(t0)
+	if len(times) != 5 || !times[0].Equal(t0.Add(-100*time.Millisecond)) || !times[4].Equal(t0.Add(500*time.Millisecond)) {
+		t.Errorf("Times = %v", times)
+	}
+
+	if got := (Session{}).Plan(); got.Attempts != 1 {
+		t.Errorf("未配置时应只尝试一次: %+v", got)
+	}
+	for _, b := range []Burst{{Attempts: MaxBurstAttempts + 1}, {Interval: "0s"}, {Lead: "10s"}, {Lead: "soon"}} {
+		if _, err := b.Plan(); err == nil {
+			t.Errorf("%+v 应报错", b)
+		}
+	}
+}
+
+func TestSessionMaxItemsFor(t *testing.T) {
+	s := Session{
+		Name:     "morning",
+		Items:    []string{"10元话费", "5元话费", "1元话费"},
+		MaxItems: 1,
+		Overrides: map[string]SessionOverride{
+			"13800138000": {MaxItems: 2},
+			"13900139000": {Items: []string{"5元话费"}},
+		},
+	}
+	for phone, want := range map[string]int{"13800138000": 2, "13900139000": 1, "13700137000": 1} {
+		if got := s.MaxItemsFor(phone); got != want {
+			t.Errorf("MaxItemsFor(%s) = %d，期望 %d", phone, got, want)
+		}
+	}
+	if got := (Session{}).MaxItemsFor("13800138000"); got != 0 {
+		t.Errorf("未配置时应不限: %d", got)
+	}
+}
@@ -0,0 +1,305 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"HighFrequencyTrading/catalog"
+	"HighFrequencyTrading/sign"
+	"HighFrequencyTrading/store"
+)
+
+// MinPasswordLen : 登录时会截取密码前 6 位，短于此长度的密码无法登录
+const MinPasswordLen = 6
+
+var phonePattern = regexp.MustCompile(`^1[3-9]\d{9}$`)
+
+// Problem : 校验发现的单个问题
+type Problem struct {
+	Location string // 问题位置，如 telecom.yaml: accounts[1].phone
+	Message  string
+}
+
+func (p Problem) String() string {
+	return p.Location + ": " + p.Message
+}
+
+// Validate : 深度校验账号与策略，返回所有发现的问题；
+// knownItems 为可兑换商品标题列表，用于检查策略中的商品是否存在
+func Validate(cfg *Config, knownItems []string) []Problem {
+	v := &validator{known: knownItems}
+
+	v.checkAccounts(cfg)
+	v.checkTradeHour(cfg)
+	v.checkStrategy(cfg)
+	v.checkStorage(cfg)
+	v.checkCache(cfg)
+	v.checkRetention(cfg)
+	v.checkLogin(cfg)
+	v.checkEndpoints(cfg)
+	v.checkCatalog(cfg)
+	return v.problems
+}
+
+type validator struct {
+	known    []string
+	problems []Problem
+}
+
+func (v *validator) add(location, format string, args ...interface{}) {
+	v.problems = append(v.problems, Problem{Location: location, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) checkAccounts(cfg *Config) {
+	if cfg.accountsFrom == "jdhf" {
+		// 逐条检查原始字符串，ParseJdhf 会跳过格式错误的条目
+		for i, entry := range strings.Split(cfg.Jdhf, "&") {
+			if len(strings.Split(entry, "#")) < 2 {
+				v.add(fmt.Sprintf("jdhf[%d]", i), "账号格式错误，应为 phone#password#uid")
+			}
+		}
+	}
+	if len(cfg.Accounts) == 0 {
+		v.add("accounts", "未配置任何账号 (--config / --jdhf / 环境变量 jdhf)")
+		return
+	}
+
+	seen := make(map[string]int)
+	for i, acc := range cfg.Accounts {
+		loc := v.accountLocation(cfg, i)
+		if !phonePattern.MatchString(acc.Phone) {
+			v.add(loc+".phone", "手机号 %q 格式不正确，应为 11 位数字", acc.Phone)
+		}
+		if first, ok := seen[acc.Phone]; ok {
+			v.add(loc+".phone", "手机号 %s 与 %s 重复", acc.Phone, v.accountLocation(cfg, first))
+		} else {
+			seen[acc.Phone] = i
+		}
+		switch acc.AuthMethod() {
+		case sign.AuthPassword:
+			if len(acc.Password) < MinPasswordLen {
+				v.add(loc+".password", "密码长度不足 %d 位", MinPasswordLen)
+			}
+		case sign.AuthSMS:
+		case sign.AuthTicket:
+			if acc.Ticket == "" {
+				v.add(loc+".ticket", "登录方式为 ticket 但未配置 ticket")
+			}
+		default:
+			v.add(loc+".auth", "登录方式 %q 未知，可选: %s", acc.Auth, strings.Join(sign.AuthMethods, " / "))
+		}
+		for j, it := range acc.Items {
+			v.checkItem(fmt.Sprintf("%s.items[%d]", loc, j), it)
+		}
+		for j, s := range acc.Sessions {
+			if _, ok := FindSession(cfg.Sessions, s); !ok {
+				v.add(fmt.Sprintf("%s.sessions[%d]", loc, j), "场次 %q 不存在，可选: %s", s, sessionNames(cfg.Sessions))
+			}
+		}
+	}
+}
+
+func (v *validator) accountLocation(cfg *Config, i int) string {
+	if cfg.accountsFrom == "jdhf" {
+		return fmt.Sprintf("jdhf[%d]", i)
+	}
+	return fmt.Sprintf("%s: accounts[%d]", cfg.accountsFrom, i)
+}
+
+func (v *validator) checkStorage(cfg *Config) {
+	switch cfg.Storage {
+	case "", store.BackendJSON, store.BackendSQLite:
+	default:
+		v.add("storage", "未知的存储后端 %q，可选 %s / %s", cfg.Storage, store.BackendJSON, store.BackendSQLite)
+	}
+}
+
+func (v *validator) checkCache(cfg *Config) {
+	if cfg.ticketTTLRaw == "" {
+		return
+	}
+	if ttl, err := time.ParseDuration(cfg.ticketTTLRaw); err != nil || ttl < 0 {
+		v.add("cache.ttl", "ticket 有效期 %q 无效，应为 12h、30m 这样的时长", cfg.ticketTTLRaw)
+	}
+}
+
+func (v *validator) checkRetention(cfg *Config) {
+	if cfg.KeepMonths < 0 {
+		v.add("retention.keepMonths", "不能为负数: %d", cfg.KeepMonths)
+	}
+	if cfg.PruneMonths < 0 {
+		v.add("retention.pruneMonths", "不能为负数: %d", cfg.PruneMonths)
+	}
+	if cfg.KeepMonths > 0 && cfg.PruneMonths > 0 && cfg.PruneMonths < cfg.KeepMonths {
+		v.add("retention.pruneMonths", "%d 小于 keepMonths %d，记录会在归档前被删除", cfg.PruneMonths, cfg.KeepMonths)
+	}
+}
+
+func (v *validator) checkLogin(cfg *Config) {
+	if cfg.MaxLoginFailures < 0 {
+		v.add("login.maxFailures", "不能为负数: %d", cfg.MaxLoginFailures)
+	}
+}
+
+func (v *validator) checkCatalog(cfg *Config) {
+	if cfg.CatalogFile == "" {
+		return
+	}
+	if _, err := catalog.ReadFile(cfg.CatalogFile); err != nil {
+		v.add("catalog.file", "%v", err)
+	}
+}
+
+func (v *validator) checkEndpoints(cfg *Config) {
+	for _, it := range endpointEnvs {
+		raw := *it.field(&cfg.Endpoints)
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			v.add(it.key, "接口地址 %q 无效，应为 http(s)://host[:port] 形式", raw)
+		}
+	}
+}
+
+func (v *validator) checkTradeHour(cfg *Config) {
+	if env := os.Getenv("CTIME"); env != "" {
+		if _, err := strconv.Atoi(env); err != nil {
+			v.add("CTIME", "交易时段 %q 不是整数", env)
+		}
+	}
+	// 来自配置文件 strategy.tradeHour / strategy.session 的值由 checkStrategy 定位到文件
+	if cfg.H != nil && cfg.H != cfg.Strategy.TradeHour && !hasSessionAtHour(cfg.Sessions, *cfg.H) {
+		v.add("trade-hour", "交易时段 %d 无效，没有在该小时开场的场次", *cfg.H)
+	}
+	if cfg.Session != "" && cfg.Session != cfg.Strategy.Session {
+		if _, ok := FindSession(cfg.Sessions, cfg.Session); !ok {
+			v.add("session", "场次 %q 不存在，可选: %s", cfg.Session, sessionNames(cfg.Sessions))
+		}
+	}
+}
+
+func (v *validator) checkBurst(loc string, b *Burst) {
+	if _, err := b.Plan(); err != nil {
+		v.add(loc, "%v", err)
+	}
+}
+
+func (v *validator) checkStrategy(cfg *Config) {
+	if cfg.strategyFrom == "MEXZ" {
+		st, err := ParseMEXZ(cfg.MEXZ)
+		if err != nil {
+			v.add("MEXZ", "%q: %v", cfg.MEXZ, err)
+			return
+		}
+		for i, s := range st.Sessions {
+			loc := fmt.Sprintf("MEXZ[%d]", i)
+			if len(s.Items) == 0 {
+				v.add(loc, "场次 %s 未配置任何商品", s.Name)
+			}
+			for j, it := range s.Items {
+				v.checkItem(fmt.Sprintf("%s[%d]", loc, j), it)
+			}
+		}
+		v.checkDuplicateSessions("MEXZ", cfg.Sessions)
+		return
+	}
+
+	prefix := cfg.strategyFrom + ": strategy"
+	st := cfg.Strategy
+	if st.TradeHour != nil && !hasSessionAtHour(cfg.Sessions, *st.TradeHour) {
+		v.add(prefix+".tradeHour", "交易时段 %d 无效，没有在该小时开场的场次", *st.TradeHour)
+	}
+	if st.Session != "" {
+		if _, ok := FindSession(cfg.Sessions, st.Session); !ok {
+			v.add(prefix+".session", "场次 %q 不存在，可选: %s", st.Session, sessionNames(cfg.Sessions))
+		}
+	}
+	if len(cfg.Sessions) == 0 {
+		v.add(prefix, "未配置任何场次 (morning / afternoon / sessions)")
+	}
+	v.checkBurst(prefix+".burst", st.Burst)
+	for j, it := range st.Morning {
+		v.checkItem(fmt.Sprintf("%s.morning[%d]", prefix, j), it)
+	}
+	for j, it := range st.Afternoon {
+		v.checkItem(fmt.Sprintf("%s.afternoon[%d]", prefix, j), it)
+	}
+
+	phones := make(map[string]bool)
+	for _, acc := range cfg.Accounts {
+		phones[acc.Phone] = true
+	}
+	for i, s := range st.Sessions {
+		loc := fmt.Sprintf("%s.sessions[%d]", prefix, i)
+		if s.Name == "" {
+			v.add(loc+".name", "场次名不能为空")
+		}
+		if _, _, _, err := s.Clock(); err != nil {
+			v.add(loc+".start", "%v", err)
+		}
+		if len(s.Items) == 0 {
+			v.add(loc+".items", "场次未配置任何商品")
+		}
+		for j, it := range s.Items {
+			v.checkItem(fmt.Sprintf("%s.items[%d]", loc, j), it)
+		}
+		v.checkBurst(loc+".burst", s.Burst)
+		if s.MaxItems < 0 {
+			v.add(loc+".maxItems", "不能为负数: %d", s.MaxItems)
+		}
+		for phone, o := range s.Overrides {
+			oloc := fmt.Sprintf("%s.overrides[%s]", loc, phone)
+			if !phones[phone] {
+				v.add(oloc, "账号 %s 未在 accounts 中配置", phone)
+			}
+			for j, it := range o.Items {
+				v.checkItem(fmt.Sprintf("%s.items[%d]", oloc, j), it)
+			}
+			if o.MaxItems < 0 {
+				v.add(oloc+".maxItems", "不能为负数: %d", o.MaxItems)
+			}
+		}
+	}
+	v.checkDuplicateSessions(prefix, cfg.Sessions)
+}
+
+func (v *validator) checkDuplicateSessions(location string, sessions []Session) {
+	seen := make(map[string]bool)
+	for _, s := range sessions {
+		if seen[s.Name] {
+			v.add(location, "场次名 %q 重复", s.Name)
+		}
+		seen[s.Name] = true
+	}
+}
+
+func hasSessionAtHour(sessions []Session, hour int) bool {
+	for _, s := range sessions {
+		if h, _, _, err := s.Clock(); err == nil && h == hour {
+			return true
+		}
+	}
+	return false
+}
+
+func sessionNames(sessions []Session) string {
+	var names []string
+	for _, s := range sessions {
+		names = append(names, s.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+func (v *validator) checkItem(location, raw string) {
+	title := ItemTitle(strings.TrimSpace(raw))
+	for _, k := range v.known {
+		if k == title {
+			return
+		}
+	}
+	v.add(location, "商品 %q 不存在，可选: %s", title, strings.Join(v.known, ", "))
+}
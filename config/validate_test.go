@@ -0,0 +1,74 @@
+// validate_test.go
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+var testItems = []string{"0.5元话费", "5元话费", "1元话费", "10元话费"}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	t.Setenv("TELECOM_DATA_DIR", t.TempDir())
+	t.Setenv("jdhf", "")
+	t.Setenv("MEXZ", "")
+	t.Setenv("CTIME", "")
+	path := writeFile(t, "telecom.yaml", `
+accounts:
+  - phone: "1380013800"
+    password: "123"
+    items: ["7"]
+  - phone: "13900139000"
+    password: "1234567"
+    sessions: ["9"]
+strategy:
+  tradeHour: 12
+  morning: ["0.5", "2"]
+  afternoon: ["10"]
+`)
+	cfg, err := NewConfig(Options{ConfigFile: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	problems := Validate(cfg, testItems)
+	want := []string{
+		"accounts[0].phone",
+		"accounts[0].password",
+		"accounts[0].items[0]",
+		"accounts[1].sessions[0]",
+		"strategy.tradeHour",
+		"strategy.morning[1]",
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("预期 %d 个问题，实际 %d: %v", len(want), len(problems), problems)
+	}
+	for i, loc := range want {
+		if !strings.HasSuffix(problems[i].Location, loc) {
+			t.Errorf("问题 %d 位置预期 %s，实际 %s", i, loc, problems[i].Location)
+		}
+	}
+}
+
+func TestValidateMEXZ(t *testing.T) {
+	t.Setenv("TELECOM_DATA_DIR", t.TempDir())
+	t.Setenv("jdhf", "13800138000#123456")
+	t.Setenv("CTIME", "")
+	for _, tt := range []struct {
+		mexz string
+		want int
+	}{
+		{"0.5,5;1,10", 0},
+		{"0.5,5", 1},
+		{"0.5,7;", 2},
+	} {
+		t.Setenv("MEXZ", tt.mexz)
+		cfg, err := NewConfig(Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := Validate(cfg, testItems); len(got) != tt.want {
+			t.Errorf("MEXZ=%q 预期 %d 个问题，实际 %v", tt.mexz, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+// Package endpoint 管理所有对外请求的基础地址。
+// sign、exchange、push 发出的请求都经由这里拼接 URL，
+// 因此可以整体指向预发环境、本地桩服务或测试服务器
+package endpoint
+
+import (
+	"strings"
+	"sync"
+)
+
+// 默认的线上地址
+const (
+	DefaultLogin    = "https://appgologin.189.cn:9031" // 登录、短信验证码
+	DefaultTicket   = "https://appgologin.189.cn:9031" // clientXML 换取 ticket
+	DefaultMall     = "https://wapact.189.cn:9001"     // 金豆商城：ticket 校验、兑换
+	DefaultWxPusher = "https://wxpusher.zjiecode.com"  // 消息推送
+)
+
+// Endpoints : 各接口的基础地址（协议 + 主机 + 端口，可带路径前缀），为空表示使用默认值
+type Endpoints struct {
+	Login    string `yaml:"login,omitempty" toml:"login" json:"login,omitempty"`
+	Ticket   string `yaml:"ticket,omitempty" toml:"ticket" json:"ticket,omitempty"`
+	Mall     string `yaml:"mall,omitempty" toml:"mall" json:"mall,omitempty"`
+	WxPusher string `yaml:"wxpusher,omitempty" toml:"wxpusher" json:"wxpusher,omitempty"`
+}
+
+// Default : 线上地址
+func Default() Endpoints {
+	return Endpoints{Login: DefaultLogin, Ticket: DefaultTicket, Mall: DefaultMall, WxPusher: DefaultWxPusher}
+}
+
+// WithDefaults : 未设置的地址补为默认值，并去掉末尾的 /
+func (e Endpoints) WithDefaults() Endpoints {
+	def := Default()
+	pick := func(v, fallback string) string {
+		if v = strings.TrimRight(strings.TrimSpace(v), "/"); v == "" {
+			return fallback
+		}
+		return v
+	}
+	return Endpoints{
+		Login:    pick(e.Login, def.Login),
+		Ticket:   pick(e.Ticket, def.Ticket),
+		Mall:     pick(e.Mall, def.Mall),
+		WxPusher: pick(e.WxPusher, def.WxPusher),
+	}
+}
+
+var (
+	mu      sync.RWMutex
+	current = Default()
+)
+
+// Set : 替换进程内使用的地址，未设置的项使用默认值
+func Set(e Endpoints) {
+	mu.Lock()
+	defer mu.Unlock()
+	current = e.WithDefaults()
+}
+
+// Get : 当前使用的地址
+func Get() Endpoints {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// Login : 登录接口的完整 URL，path 以 / 开头
+func Login(path string) string { return Get().Login + path }
+
+// Ticket : ticket 接口的完整 URL
+func Ticket(path string) string { return Get().Ticket + path }
+
+// Mall : 金豆商城接口的完整 URL
+func Mall(path string) string { return Get().Mall + path }
+
+// WxPusher : 推送接口的完整 URL
+func WxPusher(path string) string { return Get().WxPusher + path }
@@ -0,0 +1,50 @@
+package exchange
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// balancePath 金豆余额查询接口
+const balancePath = "/gateway/golden/api/queryBalance"
+
+// Balance 查询账号的金豆余额，登录失效时重新登录并重试一次
+func (s *MallSession) Balance() (int, error) {
+	for retried := false; ; retried = true {
+		resp, err := s.Post(balancePath, "{}")
+		if err != nil {
+			return 0, err
+		}
+		data, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		resp.Body.Close()
+		if err != nil {
+			return 0, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return 0, fmt.Errorf("查询金豆余额失败: HTTP %d", resp.StatusCode)
+		}
+		var reply struct {
+			Code json.Number `json:"code"`
+			Msg  string      `json:"msg"`
+			Biz  struct {
+				Amount *int `json:"amount"`
+			} `json:"biz"`
+		}
+		if err := json.Unmarshal(data, &reply); err != nil {
+			return 0, fmt.Errorf("金豆余额响应无法解析: %v", err)
+		}
+		code := reply.Code.String()
+		if Classify(resp.StatusCode, code, reply.Msg) == OutcomeSessionExpired && !retried {
+			if err := s.Refresh(); err != nil {
+				return 0, fmt.Errorf("%w: %v", ErrSessionExpired, err)
+			}
+			continue
+		}
+		if code != "0" || reply.Biz.Amount == nil {
+			return 0, fmt.Errorf("查询金豆余额失败: code=%s %s", code, reply.Msg)
+		}
+		return *reply.Biz.Amount, nil
+	}
+}
@@ -0,0 +1,264 @@
+package exchange
+
+import (
+	"HighFrequencyTrading/endpoint"
+	"HighFrequencyTrading/sign"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Stage 演练时间线中的请求阶段
+type Stage string
+
+const (
+	StageEmpty    Stage = "抢发" // 开场前 3 秒的空请求
+	StageWarmup   Stage = "预热" // 开场前 1 秒带 warmupFlag 的兑换请求
+	StageExchange Stage = "兑换" // 按尝试计划发出的兑换请求
+)
+
+// timelineShown 时间线中每一行最多列出的时间点，其余只计数
+const timelineShown = 6
+
+// Recorded 记录器收到的一次兑换接口请求
+type Recorded struct {
+	Time       time.Time
+	Phone      string
+	Stage      Stage
+	ActivityID string
+	Outcome    Outcome // 记录器给出的应答
+}
+
+// planned 计划在 Times 发出的一组请求
+type planned struct {
+	Phone, Title, ActivityID string
+	Stage                    Stage
+	Times                    []time.Time
+}
+
+// Recorder 演练模式下代替商城接收兑换接口的请求：只记录，不转发。
+// 开场前按活动未开始应答，开场后按兑换成功应答；登录、余额等其它请求照常发出
+type Recorder struct {
+	Open time.Time // 开场时间，时间线中的偏移以它为准
+
+	mu       sync.Mutex
+	requests []Recorded
+	plans    []planned
+}
+
+// NewRecorder 创建开场时间为 open 的记录器
+func NewRecorder(open time.Time) *Recorder {
+	return &Recorder{Open: open}
+}
+
+// Plan 登记计划中的请求时间，title 为空表示不针对具体商品
+func (r *Recorder) Plan(phone, title, aid string, stage Stage, times ...time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.plans = append(r.plans, planned{Phone: phone, Title: title, ActivityID: aid, Stage: stage, Times: times})
+}
+
+// Requests 按时间顺序返回收到的请求
+func (r *Recorder) Requests() []Recorded {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := append([]Recorded(nil), r.requests...)
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Time.Before(out[j].Time) })
+	return out
+}
+
+// reply 记录一次请求并给出应答
+func (r *Recorder) reply(req *http.Request, phone string) (*http.Response, error) {
+	rec := Recorded{Time: time.Now(), Phone: phone, Stage: StageEmpty, Outcome: OutcomeNotStarted}
+	if req.Body != nil {
+		var body struct {
+			ActivityID string `json:"activityId"`
+			WarmupFlag bool   `json:"warmupFlag"`
+		}
+		data, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		json.Unmarshal(data, &body)
+		rec.ActivityID, rec.Stage = body.ActivityID, StageExchange
+		if body.WarmupFlag {
+			rec.Stage = StageWarmup
+		}
+	}
+	if rec.Stage == StageExchange && !rec.Time.Before(r.Open) {
+		rec.Outcome = OutcomeSuccess
+	}
+	r.mu.Lock()
+	r.requests = append(r.requests, rec)
+	r.mu.Unlock()
+
+	code, msg := "-1", "活动未开始（演练）"
+	if rec.Outcome == OutcomeSuccess {
+		code, msg = "0", "兑换成功（演练）"
+	}
+	return &http.Response{
+		Status:     "200 OK",
+		StatusCode: http.StatusOK,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(fmt.Sprintf(`{"code":%q,"msg":%q}`, code, msg))),
+		Request:    req,
+	}, nil
+}
+
+// dryRunAllowed 演练时照常发往商城的非 GET 接口，其余一律拒绝，避免新增的接口在演练中真正兑换
+var dryRunAllowed = []string{sign.MallLoginPath, balancePath}
+
+// recordTransport 把账号的兑换接口请求交给记录器，登录、余额查询与 GET 请求经 next 发出，
+// 其余请求直接报错
+type recordTransport struct {
+	rec   *Recorder
+	phone string
+	next  http.RoundTripper
+}
+
+func (t *recordTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	path := mallPath(req.URL)
+	if path == exchangePath {
+		return t.rec.reply(req, t.phone)
+	}
+	if req.Method != http.MethodGet && !InStringArray(path, dryRunAllowed) {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, fmt.Errorf("演练时不发出 %s %s", req.Method, req.URL.Path)
+	}
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return next.RoundTrip(req)
+}
+
+// mallPath 去掉商城地址中的路径前缀后的接口路径；不是发往商城的请求返回完整路径
+func mallPath(u *url.URL) string {
+	base, err := url.Parse(endpoint.Get().Mall)
+	if err != nil || base.Host != u.Host {
+		return u.Path
+	}
+	return strings.TrimPrefix(u.Path, strings.TrimRight(base.Path, "/"))
+}
+
+// Record 让会话的兑换请求改由 r 接收，用于演练
+func (s *MallSession) Record(r *Recorder) {
+	s.Client.Transport = &recordTransport{rec: r, phone: s.Phone, next: s.Client.Transport}
+	s.recorder = r
+}
+
+// Recorder 会话使用的记录器，未在演练时为 nil
+func (s *MallSession) Recorder() *Recorder {
+	return s.recorder
+}
+
+// WriteTimeline 按账号与商品输出计划与实际的请求时间，偏移相对开场时间
+func (r *Recorder) WriteTimeline(w io.Writer) {
+	r.mu.Lock()
+	plans := append([]planned(nil), r.plans...)
+	r.mu.Unlock()
+	requests := r.Requests()
+
+	type key struct {
+		phone, aid string
+		stage      Stage
+	}
+	titles := make(map[key]string)
+	want := make(map[key][]time.Time)
+	got := make(map[key][]Recorded)
+	var keys []key
+	seen := make(map[key]bool)
+	add := func(k key) {
+		if !seen[k] {
+			seen[k] = true
+			keys = append(keys, k)
+		}
+	}
+	for _, p := range plans {
+		k := key{p.Phone, p.ActivityID, p.Stage}
+		titles[k] = p.Title
+		want[k] = append(want[k], p.Times...)
+		add(k)
+	}
+	for _, req := range requests {
+		k := key{req.Phone, req.ActivityID, req.Stage}
+		if req.Stage == StageWarmup {
+			// 预热请求轮流针对各商品，按阶段汇总
+			k.aid = ""
+		}
+		got[k] = append(got[k], req)
+		add(k)
+	}
+	// 按账号、阶段先后排列，同一阶段内保持计划登记的顺序
+	order := map[Stage]int{StageEmpty: 0, StageWarmup: 1, StageExchange: 2}
+	sort.SliceStable(keys, func(i, j int) bool {
+		if keys[i].phone != keys[j].phone {
+			return keys[i].phone < keys[j].phone
+		}
+		return order[keys[i].stage] < order[keys[j].stage]
+	})
+
+	fmt.Fprintf(w, "演练时间线（开场 %s，偏移相对开场）:\n", r.Open.Format("2006-01-02 15:04:05"))
+	if len(keys) == 0 {
+		fmt.Fprintln(w, "  无计划或发出的请求")
+		return
+	}
+	phone := ""
+	for _, k := range keys {
+		if k.phone != phone {
+			phone = k.phone
+			fmt.Fprintf(w, "%s\n", phone)
+		}
+		name := string(k.stage)
+		if t := titles[k]; t != "" {
+			name += " " + t
+		} else if k.aid != "" {
+			name += " " + k.aid
+		}
+		var planned, actual []string
+		for _, t := range want[k] {
+			planned = append(planned, r.offset(t))
+		}
+		for _, req := range got[k] {
+			s := r.offset(req.Time)
+			if req.Stage == StageExchange {
+				s += " " + string(req.Outcome)
+			}
+			actual = append(actual, s)
+		}
+		fmt.Fprintf(w, "  %s\n    计划: %s\n    实际: %s\n", name, listTimes(planned), listTimes(actual))
+	}
+}
+
+// offset 时间点相对开场的偏移，如 -1.000s、+0.050s
+func (r *Recorder) offset(t time.Time) string {
+	d := t.Sub(r.Open)
+	sign := "+"
+	if d < 0 {
+		sign, d = "-", -d
+	}
+	return fmt.Sprintf("%s%.3fs", sign, d.Seconds())
+}
+
+// listTimes 列出前 timelineShown 个时间点与总数
+func listTimes(ts []string) string {
+	if len(ts) == 0 {
+		return "无"
+	}
+	if len(ts) <= timelineShown {
+		return strings.Join(ts, ", ")
+	}
+	return fmt.Sprintf("%s … 共 %d 次", strings.Join(ts[:timelineShown], ", "), len(ts))
+}
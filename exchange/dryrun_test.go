@@ -0,0 +1,66 @@
+package exchange
+
+import (
+	"HighFrequencyTrading/config"
+	"HighFrequencyTrading/endpoint"
+	"HighFrequencyTrading/internal/fakect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecorderDryRun(t *testing.T) {
+	ct := fakect.New()
+	defer ct.Close()
+	original := endpoint.Get()
+	endpoint.Set(ct.Endpoints())
+	defer endpoint.Set(original)
+
+	const phone = "13800138000"
+	sess, err := NewMallSession(phone, ct.IssueTicket(phone))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Dh 的开场时间精确到秒
+	open := time.Now().Truncate(time.Second).Add(time.Second)
+	rec := NewRecorder(open)
+	sess.Record(rec)
+	if sess.Recorder() != rec {
+		t.Fatal("会话应使用登记的记录器")
+	}
+
+	g := &config.GlobalVars{Yf: "202501", Dhjl: map[string]map[string][]string{"202501": {}}, DryRun: true}
+	plan := config.AttemptPlan{Attempts: 3, Interval: 100 * time.Millisecond, Lead: 100 * time.Millisecond}
+
+	// Dh 登记计划时间；开场前应答未开始，开场后应答成功；请求不到达商城，也不记录兑换日志
+	if got := Dh(g, "test", phone, "5元话费", "aid_5", float64(open.Unix()), plan, "", sess); got != OutcomeSuccess {
+		t.Errorf("Dh = %s，期望 success", got)
+	}
+	if n := len(ct.Exchanges()); n != 0 {
+		t.Errorf("商城收到 %d 次兑换请求，期望 0", n)
+	}
+	if len(g.Dhjl["202501"]["5元话费"]) != 0 {
+		t.Errorf("演练不应记录兑换日志: %v", g.Dhjl)
+	}
+	reqs := rec.Requests()
+	if len(reqs) != 2 || reqs[0].Outcome != OutcomeNotStarted || reqs[1].Outcome != OutcomeSuccess || reqs[1].ActivityID != "aid_5" {
+		t.Errorf("记录的请求不符: %+v", reqs)
+	}
+
+	// 余额等其它请求照常发往商城
+	if _, err := sess.Balance(); err != nil {
+		t.Errorf("演练时应照常查询余额: %v", err)
+	}
+	// 未列入白名单的商城 POST 一律拒绝
+	if _, err := sess.Post("/gateway/other", "{}"); err == nil {
+		t.Error("演练时应拒绝未知的商城 POST 请求")
+	}
+
+	var b strings.Builder
+	rec.WriteTimeline(&b)
+	for _, want := range []string{phone, "兑换 5元话费", "计划: -0.100s, +0.000s, +0.100s", "not_started", "success"} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("时间线中缺少 %q:\n%s", want, b.String())
+		}
+	}
+}
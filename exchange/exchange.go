@@ -2,49 +2,113 @@ package exchange
 
 import (
 	"HighFrequencyTrading/config"
+	"HighFrequencyTrading/ledger"
 	"HighFrequencyTrading/push"
 	"HighFrequencyTrading/util"
+	"encoding/json"
 	"fmt"
+	"io"
 	"log"
-	"net/http"
 	"os"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // 这里原先有一个 dhjlMutex，现在已去除，统一使用 g.Mu
 
-// One 发送最终兑换请求，成功后记录手机号
-func One(g *config.GlobalVars, phone, title, aid, uid string, client *http.Client) {
-	url := "https://wapact.189.cn:9001/gateway/standExchange/detailNew/exchange"
+// exchangePath 金豆商城兑换接口
+const exchangePath = "/gateway/standExchange/detailNew/exchange"
+
+// One 经账号的商城会话发送最终兑换请求并返回归类后的结果。
+// 每次尝试都追加到账本，只有确认兑换成功时才记录手机号；演练时两者都不写
+func One(g *config.GlobalVars, session, phone, title, aid, uid string, sess *MallSession) Outcome {
 	body := fmt.Sprintf(`{"activityId":"%s"}`, aid)
-	resp, err := client.Post(url, "application/json", strings.NewReader(body))
-	if err != nil {
+	rec := ledger.Record{
+		Time:       time.Now(),
+		Session:    session,
+		Phone:      phone,
+		Item:       title,
+		ActivityID: aid,
+	}
+	status, code, msg, err := sess.postExchange(body)
+	rec.LatencyMs = time.Since(rec.Time).Milliseconds()
+	if status == 0 {
+		// 没有拿到响应，请求可能已被受理，结果未知
 		log.Printf("[One] err=%v phone=%s", err, phone)
+		rec.Outcome = ledger.OutcomeError
+		rec.Message = err.Error()
+		appendLedger(g, rec)
+		return OutcomeUnknown
+	}
+	rec.HTTPStatus = status
+	rec.Code, rec.Message = code, msg
+
+	outcome := Classify(status, code, msg)
+	if err != nil {
+		rec.Message = err.Error()
+	}
+	rec.Outcome = string(outcome)
+	appendLedger(g, rec)
+
+	if outcome != OutcomeSuccess {
+		log.Printf("[One] phone=%s title=%s outcome=%s status=%d code=%s msg=%s", phone, title, outcome, status, code, msg)
+		return outcome
+	}
+	log.Printf("[One] %s 兑换 %s 成功", phone, title)
+	if g.DryRun {
+		// 演练的结果不计入兑换日志
+		return outcome
+	}
+
+	// 账本已落盘，Dhjl 只是内存中的汇总，写 Dhjl 需要加写锁
+	g.Mu.Lock()
+	if !InStringArray(phone, g.Dhjl[g.Yf][title]) {
+		g.Dhjl[g.Yf][title] = append(g.Dhjl[g.Yf][title], phone)
+	}
+	g.Mu.Unlock()
+	return outcome
+}
+
+// appendLedger 追加账本记录，失败只记日志，不影响兑换流程
+func appendLedger(g *config.GlobalVars, rec ledger.Record) {
+	if g.Store == nil || g.DryRun {
 		return
 	}
-	defer resp.Body.Close()
+	if err := g.Store.Append(rec); err != nil {
+		log.Printf("[Ledger] phone=%s 写入账本失败: %v", rec.Phone, err)
+	}
+}
 
-	if resp.StatusCode == 200 {
-		// TODO: 此处最好解析响应体JSON，确认成功再做记录
-		log.Printf("[One] %s 兑换 %s 成功", phone, title)
+// parseReply 从响应体中尽量取出业务码与提示信息，非 JSON 时返回截断后的原文
+func parseReply(r io.Reader) (code, msg string) {
+	data, _ := io.ReadAll(io.LimitReader(r, 4096))
+	var reply map[string]interface{}
+	if json.Unmarshal(data, &reply) != nil {
+		return "", truncate(strings.TrimSpace(string(data)), 200)
+	}
+	pick := func(keys ...string) string {
+		for _, k := range keys {
+			if v, ok := reply[k]; ok && v != nil {
+				return fmt.Sprint(v)
+			}
+		}
+		return ""
+	}
+	return pick("code", "resultCode"), pick("msg", "message", "resultDesc")
+}
 
-		// 写 Dhjl 需要加写锁
-		g.Mu.Lock()
-		phones := g.Dhjl[g.Yf][title]
-		phones = append(phones, phone)
-		g.Dhjl[g.Yf][title] = phones
-		// 记完日志后保存
-		g.SaveDhjl()
-		g.Mu.Unlock()
-	} else {
-		log.Printf("[One] phone=%s status=%d", phone, resp.StatusCode)
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
 	}
+	return s[:n]
 }
 
 // DoHighFreqRequests 在目标时间前3秒内发送高频空请求
-func DoHighFreqRequests(stop time.Time, phone string, client *http.Client, wg *sync.WaitGroup) {
+func DoHighFreqRequests(stop time.Time, phone string, sess *MallSession, wg *sync.WaitGroup) {
 	if wg != nil {
 		defer wg.Done()
 	}
@@ -59,7 +123,7 @@ func DoHighFreqRequests(stop time.Time, phone string, client *http.Client, wg *s
 				return
 			}
 			go func() {
-				resp, err := client.Get("https://wapact.189.cn:9001/gateway/standExchange/detailNew/exchange")
+				resp, err := sess.Get(exchangePath)
 				if err != nil {
 					log.Printf("[DoHighFreqRequests] phone=%s error: %v", phone, err)
 					return
@@ -72,7 +136,7 @@ func DoHighFreqRequests(stop time.Time, phone string, client *http.Client, wg *s
 }
 
 // DoHighFreqRealRequests 在目标时间前1秒发送真实预热请求
-func DoHighFreqRealRequests(stop time.Time, phone string, titles, aids []string, client *http.Client, wg *sync.WaitGroup) {
+func DoHighFreqRealRequests(stop time.Time, phone string, titles, aids []string, sess *MallSession, wg *sync.WaitGroup) {
 	if wg != nil {
 		defer wg.Done()
 	}
@@ -94,7 +158,7 @@ func DoHighFreqRealRequests(stop time.Time, phone string, titles, aids []string,
 			aid := aids[i]
 			go func(title, aid string) {
 				body := fmt.Sprintf(`{"activityId":"%s","warmupFlag":true}`, aid)
-				resp, err := client.Post("https://wapact.189.cn:9001/gateway/standExchange/detailNew/exchange", "application/json", strings.NewReader(body))
+				resp, err := sess.Post(exchangePath, body)
 				if err != nil {
 					log.Printf("[DoHighFreqRealRequests] phone=%s error: %v", phone, err)
 					return
@@ -106,14 +170,59 @@ func DoHighFreqRealRequests(stop time.Time, phone string, titles, aids []string,
 	}
 }
 
-// Dh 在指定时间 wt 到达后进行兑换请求
-func Dh(g *config.GlobalVars, phone, title, aid string, wt float64, uid string, client *http.Client) {
-	delay := time.Until(time.Unix(int64(wt), 0))
-	if delay > 0 {
-		time.Sleep(delay)
+// Dh 按尝试计划在开场时间 wt 前后发起兑换，session 为所属场次名。
+// 每次尝试在计划时间点单独发出，不等待前一次的响应与账本写入；
+// 任一次成功或遇到不必再试的结果（已兑完、已兑换、金豆不足）后不再发出后续尝试。
+// 返回值优先为成功，其次为最先得到的不必再试的结果，否则为最后得到的结果
+func Dh(g *config.GlobalVars, session, phone, title, aid string, wt float64, plan config.AttemptPlan, uid string, sess *MallSession) Outcome {
+	return dhAt(g, session, phone, title, aid, plan.Times(time.Unix(int64(wt), 0)), uid, sess)
+}
+
+// dhAt 在 times 的各时间点发起兑换，规则同 Dh；演练时先向记录器登记计划时间
+func dhAt(g *config.GlobalVars, session, phone, title, aid string, times []time.Time, uid string, sess *MallSession) Outcome {
+	if rec := sess.Recorder(); rec != nil {
+		rec.Plan(phone, title, aid, StageExchange, times...)
 	}
-	log.Printf("[Dh] phone=%s title=%s 开始兑换", phone, title)
-	One(g, phone, title, aid, uid, client)
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		stop  atomic.Bool
+		final Outcome
+		last  = OutcomeUnknown
+	)
+	for i, at := range times {
+		if delay := time.Until(at); delay > 0 {
+			time.Sleep(delay)
+		}
+		if stop.Load() {
+			break
+		}
+		log.Printf("[Dh] phone=%s title=%s 第 %d/%d 次兑换", phone, title, i+1, len(times))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			outcome := One(g, session, phone, title, aid, uid, sess)
+			mu.Lock()
+			defer mu.Unlock()
+			last = outcome
+			if outcome.Final() {
+				stop.Store(true)
+				if final == "" || outcome == OutcomeSuccess {
+					final = outcome
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if final != "" {
+		return final
+	}
+	return last
+}
+
+// Notify 推送一条消息，未配置推送时只记日志
+func Notify(uid, content string) {
+	sendWxPusher(uid, content)
 }
 
 // sendWxPusher 发送消息
@@ -168,10 +277,38 @@ func PushSummary(g *config.GlobalVars, uid string) {
 			builder.WriteString(fmt.Sprintf("  手机号: %s\n", phone))
 		}
 	}
+	writeBeans(&builder, g.Beans)
 	msg := builder.String()
 	sendWxPusher(uid, msg)
 }
 
+// writeBeans 按手机号输出本场前后的金豆余额，调用方需持有 g.Mu 读锁
+func writeBeans(b *strings.Builder, beans map[string]config.BeanBalance) {
+	if len(beans) == 0 {
+		return
+	}
+	phones := make([]string, 0, len(beans))
+	for phone := range beans {
+		phones = append(phones, phone)
+	}
+	sort.Strings(phones)
+	show := func(n int) string {
+		if n == config.BeansUnknown {
+			return "未知"
+		}
+		return fmt.Sprint(n)
+	}
+	b.WriteString("金豆余额:\n")
+	for _, phone := range phones {
+		bb := beans[phone]
+		if bb.After == config.BeansUnknown {
+			b.WriteString(fmt.Sprintf("  手机号: %s %s\n", phone, show(bb.Before)))
+			continue
+		}
+		b.WriteString(fmt.Sprintf("  手机号: %s %s → %s\n", phone, show(bb.Before), show(bb.After)))
+	}
+}
+
 // InStringArray 判断字符串是否在切片中
 func InStringArray(s string, arr []string) bool {
 	for _, v := range arr {
@@ -182,9 +319,8 @@ func InStringArray(s string, arr []string) bool {
 	return false
 }
 
-// CalcT 计算当日特定小时(h):00:00的时间戳
-func CalcT(h int) int64 {
-	now := time.Now()
-	tm := time.Date(now.Year(), now.Month(), now.Day(), h, 0, 0, 0, now.Location())
+// CalcT 计算场次 s 最近一次开场的时间戳（规则见 config.Session.Next）
+func CalcT(s config.Session) int64 {
+	tm, _ := s.Next(time.Now())
 	return tm.Unix()
 }
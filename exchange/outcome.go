@@ -0,0 +1,84 @@
+package exchange
+
+import (
+	"HighFrequencyTrading/ledger"
+	"net/http"
+	"strings"
+)
+
+// Outcome 一次兑换请求的结果，取值同时写入账本的 outcome 字段
+type Outcome string
+
+const (
+	OutcomeSuccess           Outcome = ledger.OutcomeSuccess // 兑换成功
+	OutcomeSoldOut           Outcome = "sold_out"            // 已兑完
+	OutcomeAlreadyRedeemed   Outcome = "already_redeemed"    // 本月已兑换或达到限兑次数
+	OutcomeInsufficientBeans Outcome = "insufficient_beans"  // 金豆不足
+	OutcomeNotStarted        Outcome = "not_started"         // 活动未开始
+	OutcomeRateLimited       Outcome = "rate_limited"        // 请求过于频繁
+	OutcomeSessionExpired    Outcome = "session_expired"     // 商城登录失效
+	OutcomeUnknown           Outcome = "unknown"             // 无法判断，包括网络错误与无法解析的响应
+)
+
+// outcomeCodes 已知的商城业务码
+var outcomeCodes = map[string]Outcome{
+	"0":   OutcomeSuccess,
+	"-1":  OutcomeNotStarted,
+	"-2":  OutcomeSessionExpired,
+	"-5":  OutcomeInsufficientBeans,
+	"-6":  OutcomeSoldOut,
+	"-7":  OutcomeAlreadyRedeemed,
+	"401": OutcomeSessionExpired,
+}
+
+// outcomeKeywords 未知业务码时按提示文字归类，按顺序匹配；成功只认业务码
+var outcomeKeywords = []struct {
+	keyword string
+	outcome Outcome
+}{
+	{"金豆不足", OutcomeInsufficientBeans},
+	{"余额不足", OutcomeInsufficientBeans},
+	{"兑完", OutcomeSoldOut},
+	{"售罄", OutcomeSoldOut},
+	{"库存不足", OutcomeSoldOut},
+	{"已兑换", OutcomeAlreadyRedeemed},
+	{"上限", OutcomeAlreadyRedeemed},
+	{"未开始", OutcomeNotStarted},
+	{"频繁", OutcomeRateLimited},
+	{"繁忙", OutcomeRateLimited},
+	{"登录", OutcomeSessionExpired},
+}
+
+// Classify 按 HTTP 状态码、业务码与提示归类兑换响应
+func Classify(status int, code, msg string) Outcome {
+	switch {
+	case status == http.StatusTooManyRequests:
+		return OutcomeRateLimited
+	case status == http.StatusUnauthorized:
+		return OutcomeSessionExpired
+	case status != http.StatusOK:
+		return OutcomeUnknown
+	}
+	if o, ok := outcomeCodes[code]; ok {
+		return o
+	}
+	if code == "" {
+		// 响应不是 JSON，msg 为原文，无法据此判断
+		return OutcomeUnknown
+	}
+	for _, k := range outcomeKeywords {
+		if strings.Contains(msg, k.keyword) {
+			return k.outcome
+		}
+	}
+	return OutcomeUnknown
+}
+
+// Final 是否不必再尝试：已成功，或再试也不会成功
+func (o Outcome) Final() bool {
+	switch o {
+	case OutcomeSuccess, OutcomeSoldOut, OutcomeAlreadyRedeemed, OutcomeInsufficientBeans:
+		return true
+	}
+	return false
+}
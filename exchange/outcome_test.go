@@ -0,0 +1,70 @@
+package exchange
+
+import (
+	"HighFrequencyTrading/config"
+	"HighFrequencyTrading/endpoint"
+	"HighFrequencyTrading/internal/fakect"
+	"net/http"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		status    int
+		code, msg string
+		want      Outcome
+	}{
+		{http.StatusOK, "0", "兑换成功", OutcomeSuccess},
+		{http.StatusOK, "-6", "商品已兑完", OutcomeSoldOut},
+		{http.StatusOK, "-7", "本月兑换次数已达上限", OutcomeAlreadyRedeemed},
+		{http.StatusOK, "-1", "活动未开始", OutcomeNotStarted},
+		{http.StatusOK, "-2", "请先登录", OutcomeSessionExpired},
+		{http.StatusOK, "5001", "您的金豆不足", OutcomeInsufficientBeans},
+		{http.StatusOK, "5002", "当前商品库存不足", OutcomeSoldOut},
+		{http.StatusOK, "5003", "操作过于频繁，请稍后再试", OutcomeRateLimited},
+		{http.StatusOK, "5004", "兑换成功", OutcomeUnknown},
+		{http.StatusOK, "", "<html>活动火爆</html>", OutcomeUnknown},
+		{http.StatusTooManyRequests, "", "Too Many Requests", OutcomeRateLimited},
+		{http.StatusUnauthorized, "", "", OutcomeSessionExpired},
+		{http.StatusBadGateway, "0", "", OutcomeUnknown},
+	}
+	for _, tt := range tests {
+		if got := Classify(tt.status, tt.code, tt.msg); got != tt.want {
+			t.Errorf("Classify(%d, %q, %q) = %s，期望 %s", tt.status, tt.code, tt.msg, got, tt.want)
+		}
+	}
+}
+
+func TestOneRecordsOnlySuccess(t *testing.T) {
+	ct := fakect.New()
+	defer ct.Close()
+	original := endpoint.Get()
+	endpoint.Set(ct.Endpoints())
+	defer endpoint.Set(original)
+
+	const phone = "13800138000"
+	sess, err := NewMallSession(phone, ct.IssueTicket(phone))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := &config.GlobalVars{Yf: "202501", Dhjl: map[string]map[string][]string{"202501": {}}}
+
+	ct.SetStock("aid_5", 0)
+	ct.InjectFaults(fakect.TooManyRequests, fakect.Malformed)
+	for _, want := range []Outcome{OutcomeRateLimited, OutcomeUnknown, OutcomeSoldOut} {
+		if got := One(g, "test", phone, "5元话费", "aid_5", "", sess); got != want {
+			t.Errorf("One = %s，期望 %s", got, want)
+		}
+	}
+	if len(g.Dhjl["202501"]["5元话费"]) != 0 {
+		t.Fatalf("未成功的兑换不应记录: %v", g.Dhjl)
+	}
+
+	ct.SetStock("aid_5", 1)
+	if got := One(g, "test", phone, "5元话费", "aid_5", "", sess); got != OutcomeSuccess {
+		t.Errorf("One = %s，期望 success", got)
+	}
+	if !InStringArray(phone, g.Dhjl["202501"]["5元话费"]) {
+		t.Error("兑换成功后应记录手机号")
+	}
+}
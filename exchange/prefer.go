@@ -0,0 +1,57 @@
+package exchange
+
+import (
+	"HighFrequencyTrading/config"
+	"log"
+	"time"
+)
+
+// Preference 偏好列表中的一个商品
+type Preference struct {
+	Title      string
+	ActivityID string
+	Price      int // 所需金豆，0 表示未知，不占预算
+}
+
+// Prefer 按偏好顺序逐个兑换，兑到 max 个后停止；当前商品未兑到（已兑完、已兑换、金豆不足、未开始、限流）时退到下一个。
+// budget 为可用金豆，小于 0 表示不限；价格超出剩余金豆的商品直接跳过。
+// 只有兑换成功与结果未知（可能已经成功）计入上限；商城登录失效时放弃后续商品。第一个商品按开场时间 wt 的计划尝试，
+// 开场计划已过时，后续商品的计划从当前时间重新开始，保持计划中的间隔。
+// 返回兑换成功的商品标题
+func Prefer(g *config.GlobalVars, session, phone string, prefs []Preference, max, budget int, wt float64, plan config.AttemptPlan, uid string, sess *MallSession) []string {
+	var got []string
+	taken := 0
+	open := time.Unix(int64(wt), 0)
+	for _, p := range prefs {
+		if taken >= max {
+			break
+		}
+		if budget >= 0 && p.Price > budget {
+			log.Printf("[Prefer] phone=%s %s 需 %d 金豆，剩余 %d，跳过", phone, p.Title, p.Price, budget)
+			continue
+		}
+		log.Printf("[Prefer] phone=%s 兑换 %s（已兑 %d/%d）", phone, p.Title, taken, max)
+		t0 := open
+		if now := time.Now().Add(plan.Lead); now.After(t0) {
+			t0 = now
+		}
+		outcome := dhAt(g, session, phone, p.Title, p.ActivityID, plan.Times(t0), uid, sess)
+		switch outcome {
+		case OutcomeSuccess:
+			got = append(got, p.Title)
+			taken++
+			if budget >= 0 {
+				budget -= p.Price
+			}
+		case OutcomeUnknown:
+			log.Printf("[Prefer] phone=%s %s 结果未知，可能已兑到，计入上限", phone, p.Title)
+			taken++
+		case OutcomeSessionExpired:
+			log.Printf("[Prefer] phone=%s 商城登录失效，放弃后续商品", phone)
+			return got
+		default:
+			log.Printf("[Prefer] phone=%s %s 未兑到（%s），尝试下一个", phone, p.Title, outcome)
+		}
+	}
+	return got
+}
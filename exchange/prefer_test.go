@@ -0,0 +1,170 @@
+package exchange
+
+import (
+	"HighFrequencyTrading/config"
+	"HighFrequencyTrading/endpoint"
+	"HighFrequencyTrading/internal/fakect"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPreferFallsBackOnSoldOut(t *testing.T) {
+	ct := fakect.New()
+	defer ct.Close()
+	original := endpoint.Get()
+	endpoint.Set(ct.Endpoints())
+	defer endpoint.Set(original)
+
+	const phone = "13800138000"
+	sess, err := NewMallSession(phone, ct.IssueTicket(phone))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := &config.GlobalVars{Yf: "202501", Dhjl: map[string]map[string][]string{"202501": {}}}
+	plan := config.AttemptPlan{Attempts: 1}
+	wt := float64(time.Now().Unix())
+
+	// 10元已兑完，退到 5元；上限 1 个，1元不再兑换
+	ct.SetStock("aid_10", 0)
+	ct.SetStock("aid_5", 1)
+	ct.SetStock("aid_1", 1)
+	prefs := []Preference{
+		{Title: "10元话费", ActivityID: "aid_10"},
+		{Title: "5元话费", ActivityID: "aid_5"},
+		{Title: "1元话费", ActivityID: "aid_1"},
+	}
+	got := Prefer(g, "test", phone, prefs, 1, -1, wt, plan, "", sess)
+	if want := []string{"5元话费"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Prefer = %v，期望 %v", got, want)
+	}
+	var aids []string
+	for _, ex := range ct.Exchanges() {
+		aids = append(aids, ex.ActivityID)
+	}
+	if want := []string{"aid_10", "aid_5"}; !reflect.DeepEqual(aids, want) {
+		t.Errorf("兑换请求 %v，期望 %v", aids, want)
+	}
+}
+
+func TestPreferBudget(t *testing.T) {
+	ct := fakect.New()
+	defer ct.Close()
+	original := endpoint.Get()
+	endpoint.Set(ct.Endpoints())
+	defer endpoint.Set(original)
+
+	const phone = "13800138000"
+	sess, err := NewMallSession(phone, ct.IssueTicket(phone))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := &config.GlobalVars{Yf: "202501", Dhjl: map[string]map[string][]string{"202501": {}}}
+	plan := config.AttemptPlan{Attempts: 1}
+	wt := float64(time.Now().Unix())
+
+	// 余额 600：兑到 5元后剩 100，跳过 2元，兑到 1元
+	ct.SetStock("aid_5", 1)
+	ct.SetStock("aid_2", 1)
+	ct.SetStock("aid_1", 1)
+	prefs := []Preference{
+		{Title: "5元话费", ActivityID: "aid_5", Price: 500},
+		{Title: "2元话费", ActivityID: "aid_2", Price: 200},
+		{Title: "1元话费", ActivityID: "aid_1", Price: 100},
+	}
+	got := Prefer(g, "test", phone, prefs, 3, 600, wt, plan, "", sess)
+	if want := []string{"5元话费", "1元话费"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Prefer = %v，期望 %v", got, want)
+	}
+	if n := len(ct.Exchanges()); n != 2 {
+		t.Errorf("发出 %d 次兑换请求，期望 2", n)
+	}
+}
+
+func TestPreferRetimesFallback(t *testing.T) {
+	ct := fakect.New()
+	defer ct.Close()
+	original := endpoint.Get()
+	endpoint.Set(ct.Endpoints())
+	defer endpoint.Set(original)
+
+	const phone = "13800138000"
+	sess, err := NewMallSession(phone, ct.IssueTicket(phone))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := &config.GlobalVars{Yf: "202501", Dhjl: map[string]map[string][]string{"202501": {}}}
+	plan := config.AttemptPlan{Attempts: 3, Interval: 100 * time.Millisecond}
+
+	// 10元已兑完，退到 5元；5元的活动不存在，结果未知，计入上限，不再兑换 1元
+	ct.SetStock("aid_10", 0)
+	ct.SetStock("aid_1", 1)
+	prefs := []Preference{
+		{Title: "10元话费", ActivityID: "aid_10"},
+		{Title: "5元话费", ActivityID: "aid_5"},
+		{Title: "1元话费", ActivityID: "aid_1"},
+	}
+	if got := Prefer(g, "test", phone, prefs, 1, -1, nextSecond(), plan, "", sess); len(got) != 0 {
+		t.Fatalf("Prefer = %v，期望未兑到", got)
+	}
+	var soldOut time.Time
+	var fallback []time.Time
+	for _, ex := range ct.Exchanges() {
+		switch ex.ActivityID {
+		case "aid_10":
+			soldOut = ex.Time
+		case "aid_5":
+			fallback = append(fallback, ex.Time)
+		default:
+			t.Errorf("不应兑换 %s", ex.ActivityID)
+		}
+	}
+	if len(fallback) != plan.Attempts {
+		t.Fatalf("5元话费尝试 %d 次，期望 %d", len(fallback), plan.Attempts)
+	}
+	// 开场计划已过，5元话费的尝试从当前时间重新按间隔发出，而不是连续发出
+	if fallback[0].Before(soldOut) {
+		t.Errorf("5元话费的首次尝试 %s 早于 10元话费的尝试 %s", fallback[0].Format("15:04:05.000"), soldOut.Format("15:04:05.000"))
+	}
+	for i := 1; i < len(fallback); i++ {
+		if gap := fallback[i].Sub(fallback[i-1]); gap < plan.Interval-30*time.Millisecond {
+			t.Errorf("第 %d 次尝试距上一次 %s，期望约 %s", i+1, gap, plan.Interval)
+		}
+	}
+}
+
+func TestPreferFallsBackOnNotStarted(t *testing.T) {
+	ct := fakect.New()
+	defer ct.Close()
+	original := endpoint.Get()
+	endpoint.Set(ct.Endpoints())
+	defer endpoint.Set(original)
+
+	const phone = "13800138000"
+	sess, err := NewMallSession(phone, ct.IssueTicket(phone))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := &config.GlobalVars{Yf: "202501", Dhjl: map[string]map[string][]string{"202501": {}}}
+	plan := config.AttemptPlan{Attempts: 1}
+
+	// 10元活动未开始，没有兑到，不计入上限，退到 5元
+	ct.OpenItemAt("aid_10", time.Now().Add(time.Hour))
+	ct.SetStock("aid_10", 1)
+	ct.SetStock("aid_5", 1)
+	prefs := []Preference{
+		{Title: "10元话费", ActivityID: "aid_10"},
+		{Title: "5元话费", ActivityID: "aid_5"},
+	}
+	got := Prefer(g, "test", phone, prefs, 1, -1, float64(time.Now().Unix()), plan, "", sess)
+	if want := []string{"5元话费"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Prefer = %v，期望 %v", got, want)
+	}
+	var codes []string
+	for _, ex := range ct.Exchanges() {
+		codes = append(codes, ex.ActivityID+":"+ex.Code)
+	}
+	if want := []string{"aid_10:" + fakect.CodeNotOpen, "aid_5:" + fakect.CodeOK}; !reflect.DeepEqual(codes, want) {
+		t.Errorf("兑换请求 %v，期望 %v", codes, want)
+	}
+}
@@ -0,0 +1,119 @@
+package exchange
+
+import (
+	"HighFrequencyTrading/endpoint"
+	"HighFrequencyTrading/sign"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrSessionExpired 商城登录已失效，用 ticket 重新登录后仍被拒绝
+var ErrSessionExpired = errors.New("商城登录已失效")
+
+// MallSession 单个账号的金豆商城登录态：独立的 Cookie Jar 与登录 token，
+// 该账号的所有兑换请求都经由它发出
+type MallSession struct {
+	Phone  string
+	Client *http.Client
+
+	ticket   string
+	recorder *Recorder // 演练时接收兑换请求，见 Record
+
+	mu         sync.RWMutex
+	token      string
+	loggedInAt time.Time
+}
+
+// NewMallSession 用 ticket 登录商城并建立会话，ticket 失效时返回包装了 sign.ErrTicketInvalid 的错误
+func NewMallSession(phone, ticket string) (*MallSession, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	s := &MallSession{
+		Phone:  phone,
+		Client: &http.Client{Timeout: 5 * time.Second, Jar: jar},
+		ticket: ticket,
+	}
+	if err := s.Refresh(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Refresh 用 ticket 重新登录商城，替换会话中的 token 与 Cookie
+func (s *MallSession) Refresh() error {
+	token, err := sign.MallLogin(s.Client, s.ticket)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.token, s.loggedInAt = token, time.Now()
+	s.mu.Unlock()
+	return nil
+}
+
+// LoggedInAt 最近一次登录商城的时间
+func (s *MallSession) LoggedInAt() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.loggedInAt
+}
+
+// Do 为请求带上登录态与商城要求的请求头后发出
+func (s *MallSession) Do(req *http.Request) (*http.Response, error) {
+	s.mu.RLock()
+	token := s.token
+	s.mu.RUnlock()
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	req.Header.Set("User-Agent", sign.CurrentProtocol().WebUserAgent)
+	req.Header.Set("Referer", sign.MallReferer())
+	return s.Client.Do(req)
+}
+
+// Post 以 JSON 请求体 POST 商城接口
+func (s *MallSession) Post(path, body string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodPost, endpoint.Mall(path), strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json;charset=UTF-8")
+	return s.Do(req)
+}
+
+// Get GET 商城接口
+func (s *MallSession) Get(path string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, endpoint.Mall(path), nil)
+	if err != nil {
+		return nil, err
+	}
+	return s.Do(req)
+}
+
+// postExchange 发送兑换请求并解析业务码；登录失效时重新登录并重试一次，
+// 重新登录失败返回包装了 ErrSessionExpired 的错误
+func (s *MallSession) postExchange(body string) (status int, code, msg string, err error) {
+	for retried := false; ; retried = true {
+		resp, err := s.Post(exchangePath, body)
+		if err != nil {
+			return 0, "", "", err
+		}
+		code, msg = parseReply(resp.Body)
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if Classify(resp.StatusCode, code, msg) != OutcomeSessionExpired || retried {
+			return resp.StatusCode, code, msg, nil
+		}
+		if err := s.Refresh(); err != nil {
+			return resp.StatusCode, code, msg, fmt.Errorf("%w: %v", ErrSessionExpired, err)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package exchange
+
+import (
+	"HighFrequencyTrading/config"
+	"HighFrequencyTrading/endpoint"
+	"HighFrequencyTrading/internal/fakect"
+	"HighFrequencyTrading/sign"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestMallSession(t *testing.T) {
+	ct := fakect.New()
+	defer ct.Close()
+	original := endpoint.Get()
+	endpoint.Set(ct.Endpoints())
+	defer endpoint.Set(original)
+	ct.SetStock("aid_5", 10)
+
+	const phone = "13800138000"
+	if _, err := NewMallSession(phone, "ticket-bogus"); !errors.Is(err, sign.ErrTicketInvalid) {
+		t.Fatalf("无效 ticket 应返回 ErrTicketInvalid: %v", err)
+	}
+	sess, err := NewMallSession(phone, ct.IssueTicket(phone))
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(ct.URL)
+	if len(sess.Client.Jar.Cookies(u)) == 0 {
+		t.Error("商城下发的 Cookie 未保存")
+	}
+
+	g := &config.GlobalVars{Yf: "202501", Dhjl: map[string]map[string][]string{"202501": {}}}
+	lastCode := func() string {
+		exs := ct.Exchanges()
+		return exs[len(exs)-1].Code
+	}
+
+	One(g, "test", phone, "5元话费", "aid_5", "", sess)
+	if exs := ct.Exchanges(); len(exs) != 1 || exs[0].Phone != phone || exs[0].Code != fakect.CodeOK {
+		t.Fatalf("兑换请求未携带登录态: %+v", exs)
+	}
+
+	// 商城登录失效：用 ticket 重新登录后重试一次
+	ct.ExpireSessions()
+	One(g, "test", phone, "5元话费", "aid_5", "", sess)
+	if exs := ct.Exchanges(); len(exs) != 3 || exs[1].Code != fakect.CodeNotLoggedIn || lastCode() != fakect.CodeOK {
+		t.Errorf("登录失效后应重新登录并重试: %+v", exs)
+	}
+	if got := ct.Granted(phone, "aid_5"); got != 2 {
+		t.Errorf("Granted = %d", got)
+	}
+
+	// ticket 也失效：重新登录失败，不再重试
+	ct.ExpireTickets()
+	if _, _, _, err := sess.postExchange(`{"activityId":"aid_5"}`); !errors.Is(err, ErrSessionExpired) {
+		t.Errorf("ticket 失效后应返回 ErrSessionExpired: %v", err)
+	}
+	if got := len(ct.Exchanges()); got != 4 || lastCode() != fakect.CodeNotLoggedIn {
+		t.Errorf("重新登录失败后不应重试: %d", got)
+	}
+}
+
+func TestDhStopsOnFinalOutcome(t *testing.T) {
+	ct := fakect.New()
+	defer ct.Close()
+	original := endpoint.Get()
+	endpoint.Set(ct.Endpoints())
+	defer endpoint.Set(original)
+
+	const phone = "13800138000"
+	sess, err := NewMallSession(phone, ct.IssueTicket(phone))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := &config.GlobalVars{Yf: "202501", Dhjl: map[string]map[string][]string{"202501": {}}}
+	plan := config.AttemptPlan{Attempts: 5, Interval: 100 * time.Millisecond}
+
+	// stopped 检查第 final 个请求之后的请求：各次尝试按计划发出、不等待响应，
+	// 收到结果前可能已有一次尝试在途，但不应在收到结果后再发出
+	stopped := func(exs []fakect.Exchange, final int) {
+		t.Helper()
+		for _, ex := range exs[final+1:] {
+			if d := ex.Time.Sub(exs[final].Time); d >= plan.Interval {
+				t.Errorf("得到结果 %s 后仍发出请求: %+v", exs[final].Code, ex)
+			}
+		}
+	}
+
+	// 前两次被限流与网关错误，第三次成功后停止
+	ct.SetStock("aid_5", 1)
+	ct.InjectFaults(fakect.TooManyRequests, fakect.BadGateway)
+	if got := Dh(g, "test", phone, "5元话费", "aid_5", nextSecond(), plan, "", sess); got != OutcomeSuccess {
+		t.Errorf("Dh = %s，期望 success", got)
+	}
+	exs := ct.Exchanges()
+	if len(exs) < 3 || exs[2].Code != fakect.CodeOK {
+		t.Fatalf("第 3 次请求应成功: %+v", exs)
+	}
+	stopped(exs, 2)
+
+	// 已兑完立即停止
+	before := len(exs)
+	if got := Dh(g, "test", phone, "5元话费", "aid_5", nextSecond(), plan, "", sess); got != OutcomeSoldOut {
+		t.Errorf("Dh = %s，期望 sold_out", got)
+	}
+	exs = ct.Exchanges()
+	if len(exs) <= before || exs[before].Code != fakect.CodeSoldOut {
+		t.Fatalf("应得到已兑完: %+v", exs[before:])
+	}
+	stopped(exs, before)
+}
+
+// 响应慢于尝试间隔时，各次尝试仍按计划时间发出
+func TestDhKeepsScheduleUnderLatency(t *testing.T) {
+	ct := fakect.New()
+	defer ct.Close()
+	original := endpoint.Get()
+	endpoint.Set(ct.Endpoints())
+	defer endpoint.Set(original)
+
+	const phone = "13800138000"
+	sess, err := NewMallSession(phone, ct.IssueTicket(phone))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := &config.GlobalVars{Yf: "202501", Dhjl: map[string]map[string][]string{"202501": {}}}
+	plan := config.AttemptPlan{Attempts: 3, Interval: 50 * time.Millisecond}
+
+	// 活动未开始，每次都需要继续尝试
+	wt := nextSecond()
+	t0 := time.Unix(int64(wt), 0)
+	ct.SetStock("aid_5", 1)
+	ct.OpenAt(t0.Add(time.Hour))
+	ct.SetLatency(150 * time.Millisecond)
+	if got := Dh(g, "test", phone, "5元话费", "aid_5", wt, plan, "", sess); got != OutcomeNotStarted {
+		t.Errorf("Dh = %s，期望 not_started", got)
+	}
+	exs := ct.Exchanges()
+	if len(exs) != 3 {
+		t.Fatalf("发出 %d 次请求，期望 3", len(exs))
+	}
+	for i, ex := range exs {
+		want := t0.Add(time.Duration(i) * plan.Interval)
+		if d := ex.Time.Sub(want); d < 0 || d > 30*time.Millisecond {
+			t.Errorf("第 %d 次请求于 %s 到达，计划 %s", i+1, ex.Time.Format("15:04:05.000"), want.Format("15:04:05.000"))
+		}
+	}
+}
+
+// nextSecond 下一个整秒，Dh 的开场时间精确到秒
+func nextSecond() float64 {
+	return float64(time.Now().Truncate(time.Second).Add(time.Second).Unix())
+}
+
+func TestMallSessionBalance(t *testing.T) {
+	ct := fakect.New()
+	defer ct.Close()
+	original := endpoint.Get()
+	endpoint.Set(ct.Endpoints())
+	defer endpoint.Set(original)
+
+	const phone = "13800138000"
+	ct.SetBeans(phone, 1234)
+	sess, err := NewMallSession(phone, ct.IssueTicket(phone))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := sess.Balance(); err != nil || n != 1234 {
+		t.Errorf("Balance = %d, %v", n, err)
+	}
+	ct.ExpireSessions()
+	if n, err := sess.Balance(); err != nil || n != 1234 {
+		t.Errorf("登录失效后应重新登录再查询: %d, %v", n, err)
+	}
+}
@@ -0,0 +1,163 @@
+package fakect
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// handleExchange 模拟兑换接口，判断顺序与线上一致：
+// 注入的异常 → 请求方法与参数 → 商城登录 → 活动是否存在 → 是否开场 → 单号限购 → 库存 → 金豆余额。
+// 商城登录以请求头 Authorization 或 Cookie 携带 /unified/user/login 返回的 token
+func (s *Server) handleExchange(w http.ResponseWriter, r *http.Request) {
+	ex := Exchange{Time: time.Now()}
+
+	s.mu.Lock()
+	latency := s.latency
+	var fault *Fault
+	if len(s.faults) > 0 {
+		f := s.faults[0]
+		s.faults = s.faults[1:]
+		fault = &f
+	}
+	s.mu.Unlock()
+
+	if fault != nil {
+		latency += fault.Latency
+	}
+	if latency > 0 {
+		time.Sleep(latency)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ex.Phone = s.mallPhone(r)
+	reply := func(status int, code, msg string) {
+		ex.Status, ex.Code = status, code
+		s.exchanges = append(s.exchanges, ex)
+		if code == "" {
+			w.WriteHeader(status)
+			io.WriteString(w, msg)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"code": code, "msg": msg})
+	}
+
+	if fault != nil {
+		reply(fault.Status, "", fault.Body)
+		return
+	}
+	if r.Method != http.MethodPost {
+		reply(http.StatusMethodNotAllowed, "", "Method Not Allowed")
+		return
+	}
+	var req struct {
+		ActivityID string `json:"activityId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ActivityID == "" {
+		reply(http.StatusOK, CodeBadRequest, "参数错误")
+		return
+	}
+	ex.ActivityID = req.ActivityID
+	if ex.Phone == "" {
+		reply(http.StatusOK, CodeNotLoggedIn, "请先登录")
+		return
+	}
+	left, ok := s.stock[req.ActivityID]
+	if !ok {
+		reply(http.StatusOK, CodeNoActivity, "活动不存在")
+		return
+	}
+	opens, ok := s.itemOpens[req.ActivityID]
+	if !ok {
+		opens = s.opensAt
+	}
+	if !opens.IsZero() && s.now().Before(opens) {
+		reply(http.StatusOK, CodeNotOpen, "活动未开始")
+		return
+	}
+	key := ex.Phone + "|" + req.ActivityID
+	if s.phoneLimit > 0 && s.granted[key] >= s.phoneLimit {
+		reply(http.StatusOK, CodeLimited, "本月兑换次数已达上限")
+		return
+	}
+	if left <= 0 {
+		reply(http.StatusOK, CodeSoldOut, "商品已兑完")
+		return
+	}
+	price := s.prices[req.ActivityID]
+	if beans := s.balance(ex.Phone); price > beans {
+		reply(http.StatusOK, CodeNoBeans, "金豆不足")
+		return
+	}
+	s.beans[ex.Phone] = s.balance(ex.Phone) - price
+	s.stock[req.ActivityID] = left - 1
+	s.granted[key]++
+	reply(http.StatusOK, CodeOK, "兑换成功")
+}
+
+// mallPhone 由 Authorization 请求头或会话 Cookie 识别已登录商城的手机号，调用方需持有锁
+func (s *Server) mallPhone(r *http.Request) string {
+	if phone, ok := s.mall[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")]; ok {
+		return phone
+	}
+	if c, err := r.Cookie(SessionCookie); err == nil {
+		return s.mall[c.Value]
+	}
+	return ""
+}
+
+func (s *Server) handleBalance(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	phone := s.mallPhone(r)
+	if phone == "" {
+		writeJSON(w, map[string]string{"code": CodeNotLoggedIn, "msg": "请先登录"})
+		return
+	}
+	writeJSON(w, map[string]interface{}{"code": CodeOK, "msg": "成功", "biz": map[string]int{"amount": s.balance(phone)}})
+}
+
+// handleCatalog 按开场时间返回商品列表与实时库存
+func (s *Server) handleCatalog(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		StartTime string `json:"startTime"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, map[string]string{"code": CodeBadRequest, "msg": "参数错误"})
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := []map[string]interface{}{}
+	for _, it := range s.items {
+		if it.start != "" && it.start != req.StartTime {
+			continue
+		}
+		list = append(list, map[string]interface{}{
+			"title":      it.title,
+			"activityId": it.activityID,
+			"price":      it.price,
+			"stock":      s.stock[it.activityID],
+		})
+	}
+	writeJSON(w, map[string]interface{}{"code": CodeOK, "msg": "成功", "biz": map[string]interface{}{"list": list}})
+}
+
+func (s *Server) handleWxPusher(w http.ResponseWriter, r *http.Request) {
+	var msg struct {
+		Content string `json:"content"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+		writeJSON(w, map[string]interface{}{"code": 1001, "msg": "参数错误", "success": false})
+		return
+	}
+	s.mu.Lock()
+	s.messages = append(s.messages, msg.Content)
+	s.mu.Unlock()
+	writeJSON(w, map[string]interface{}{"code": 1000, "msg": "处理成功", "success": true})
+}
@@ -0,0 +1,288 @@
+// Package fakect 是进程内的电信接口模拟服务，供端到端测试使用。
+// 它实现登录（userLoginNormal / getRandomCode）、换取 ticket（clientXML getSingle）、
+// 金豆商城登录、商品列表与兑换接口，以及 WxPusher 推送接口；兑换的开场时间、库存、
+// 单号限购、延迟与 429/5xx/畸形响应都可以按测试场景设置
+package fakect
+
+import (
+	"HighFrequencyTrading/endpoint"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+)
+
+// 接口路径，与线上一致
+const (
+	PathLogin     = "/login/client/userLoginNormal"
+	PathSMSCode   = "/login/client/getRandomCode"
+	PathClientXML = "/map/clientXML"
+	PathMallLogin = "/unified/user/login"
+	PathExchange  = "/gateway/standExchange/detailNew/exchange"
+	PathCatalog   = "/gateway/standExchange/detailNew/list"
+	PathBalance   = "/gateway/golden/api/queryBalance"
+	PathWxPusher  = "/api/send/message"
+)
+
+// PathPrefix : 所有接口同时挂在该前缀下，用于模拟带路径前缀的接口地址，如 s.URL + PathPrefix
+const PathPrefix = "/prefix"
+
+// DefaultSMSCode : 未单独设置时下发的短信验证码
+const DefaultSMSCode = "123456"
+
+// DefaultBeans : 未单独设置时账号的金豆余额
+const DefaultBeans = 10000
+
+// Server : 模拟服务，零值不可用，使用 New 创建
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	now       func() time.Time
+	passwords map[string]string // 手机号 -> 服务密码
+	smsCodes  map[string]string // 手机号 -> 已下发的验证码
+	users     map[string]string // userId -> 手机号
+	tokens    map[string]string // 登录 token -> 手机号
+	tickets   map[string]string // ticket -> 手机号
+	mall      map[string]string // 商城 token -> 手机号
+	seq       int
+	rejects   map[string][2]string // 手机号 -> 登录时返回的 resultCode 与 resultDesc
+	loginFail []Fault
+
+	opensAt    time.Time
+	itemOpens  map[string]time.Time
+	stock      map[string]int // activityId -> 剩余库存，不在其中的活动视为不存在
+	phoneLimit int            // 单个手机号每个活动的限购次数，0 表示不限
+	granted    map[string]int // 手机号|activityId -> 已兑换次数
+	items      []item         // 商品列表接口返回的商品
+	prices     map[string]int // activityId -> 所需金豆
+	beans      map[string]int // 手机号 -> 金豆余额，未设置时为 DefaultBeans
+	latency    time.Duration
+	faults     []Fault
+
+	exchanges []Exchange
+	messages  []string
+}
+
+// Fault : 注入的一次异常响应，按注入顺序依次作用于兑换或登录请求
+type Fault struct {
+	Status  int           // HTTP 状态码
+	Body    string        // 响应体
+	Latency time.Duration // 返回前额外等待的时长
+}
+
+// 常用的异常响应
+var (
+	TooManyRequests = Fault{Status: http.StatusTooManyRequests, Body: "Too Many Requests"}
+	BadGateway      = Fault{Status: http.StatusBadGateway, Body: "<html><body>502 Bad Gateway</body></html>"}
+	Malformed       = Fault{Status: http.StatusOK, Body: `{"code":"0","msg":`}
+)
+
+// item : 商品列表中的一个商品，库存取自 stock
+type item struct {
+	start      string // 开场时间 HH:MM:SS，为空表示所有场次
+	title      string
+	activityID string
+	price      int
+}
+
+// Exchange : 服务端收到的一次兑换请求
+type Exchange struct {
+	Time       time.Time
+	Phone      string // 未登录商城时为空
+	ActivityID string
+	Status     int    // 返回的 HTTP 状态码
+	Code       string // 返回的业务码，异常响应时为空
+}
+
+// New : 启动模拟服务，使用完毕后调用 Close
+func New() *Server {
+	s := &Server{
+		now:       time.Now,
+		passwords: make(map[string]string),
+		smsCodes:  make(map[string]string),
+		users:     make(map[string]string),
+		tokens:    make(map[string]string),
+		tickets:   make(map[string]string),
+		mall:      make(map[string]string),
+		itemOpens: make(map[string]time.Time),
+		stock:     make(map[string]int),
+		granted:   make(map[string]int),
+		prices:    make(map[string]int),
+		beans:     make(map[string]int),
+		rejects:   make(map[string][2]string),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(PathLogin, s.handleLogin)
+	mux.HandleFunc(PathSMSCode, s.handleSMSCode)
+	mux.HandleFunc(PathClientXML, s.handleClientXML)
+	mux.HandleFunc(PathMallLogin, s.handleMallLogin)
+	mux.HandleFunc(PathExchange, s.handleExchange)
+	mux.HandleFunc(PathCatalog, s.handleCatalog)
+	mux.HandleFunc(PathBalance, s.handleBalance)
+	mux.HandleFunc(PathWxPusher, s.handleWxPusher)
+	root := http.NewServeMux()
+	root.Handle("/", mux)
+	root.Handle(PathPrefix+"/", http.StripPrefix(PathPrefix, mux))
+	s.Server = httptest.NewServer(root)
+	return s
+}
+
+// Endpoints : 全部指向本服务的接口地址，交给 endpoint.Set 即可重定向所有对外请求
+func (s *Server) Endpoints() endpoint.Endpoints {
+	return endpoint.Endpoints{Login: s.URL, Ticket: s.URL, Mall: s.URL, WxPusher: s.URL}
+}
+
+// AddAccount : 注册可登录的账号
+func (s *Server) AddAccount(phone, password string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.passwords[phone] = password
+}
+
+// RejectLogin : 之后 phone 的登录均返回指定的业务码，code 为空时恢复正常
+func (s *Server) RejectLogin(phone, code, desc string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if code == "" {
+		delete(s.rejects, phone)
+		return
+	}
+	s.rejects[phone] = [2]string{code, desc}
+}
+
+// InjectLoginFaults : 追加登录接口的异常响应，之后的登录请求依次取用
+func (s *Server) InjectLoginFaults(faults ...Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loginFail = append(s.loginFail, faults...)
+}
+
+// OpenAt : 设置开场时间，此前的兑换请求返回活动未开始；零值表示已开场
+func (s *Server) OpenAt(t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.opensAt = t
+}
+
+// OpenItemAt : 单独设置某个活动的开场时间，优先于 OpenAt 设置的时间
+func (s *Server) OpenItemAt(activityID string, t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.itemOpens[activityID] = t
+}
+
+// SetStock : 设置活动的库存
+func (s *Server) SetStock(activityID string, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stock[activityID] = n
+}
+
+// AddItem : 上架商品并设置库存；start 为开场时间 HH:MM:SS，为空时在所有场次的商品列表中出现
+func (s *Server) AddItem(start, title, activityID string, price, stock int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items = append(s.items, item{start: start, title: title, activityID: activityID, price: price})
+	s.stock[activityID] = stock
+	s.prices[activityID] = price
+}
+
+// SetBeans : 设置账号的金豆余额，兑换成功时扣除商品所需金豆
+func (s *Server) SetBeans(phone string, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.beans[phone] = n
+}
+
+// Beans : 账号当前的金豆余额
+func (s *Server) Beans(phone string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.balance(phone)
+}
+
+// balance : 调用方需持有锁
+func (s *Server) balance(phone string) int {
+	if n, ok := s.beans[phone]; ok {
+		return n
+	}
+	return DefaultBeans
+}
+
+// SetPhoneLimit : 设置单个手机号每个活动的限购次数，0 表示不限
+func (s *Server) SetPhoneLimit(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.phoneLimit = n
+}
+
+// SetLatency : 每个兑换请求返回前等待的时长
+func (s *Server) SetLatency(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = d
+}
+
+// InjectFaults : 追加异常响应，之后的兑换请求依次取用，取完后恢复正常
+func (s *Server) InjectFaults(faults ...Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, faults...)
+}
+
+// ExpireTickets : 使已签发的 ticket 与商城登录全部失效
+func (s *Server) ExpireTickets() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tickets = make(map[string]string)
+	s.mall = make(map[string]string)
+}
+
+// ExpireSessions : 只使商城登录失效，ticket 仍可用于重新登录商城
+func (s *Server) ExpireSessions() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mall = make(map[string]string)
+}
+
+// IssueTicket : 直接为 phone 签发一个 ticket，模拟用户导入的 ticket
+func (s *Server) IssueTicket(phone string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.issueTicket(phone)
+}
+
+// Exchanges : 已收到的兑换请求，按到达顺序
+func (s *Server) Exchanges() []Exchange {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Exchange(nil), s.exchanges...)
+}
+
+// Granted : phone 在 activityID 上成功兑换的次数
+func (s *Server) Granted(phone, activityID string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.granted[phone+"|"+activityID]
+}
+
+// Stock : 活动的剩余库存
+func (s *Server) Stock(activityID string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stock[activityID]
+}
+
+// Messages : 收到的推送内容
+func (s *Server) Messages() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.messages...)
+}
+
+// nextID : 生成递增的标识，调用方需持有锁
+func (s *Server) nextID() int {
+	s.seq++
+	return s.seq
+}
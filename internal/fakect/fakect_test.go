@@ -0,0 +1,193 @@
+package fakect
+
+import (
+	"HighFrequencyTrading/endpoint"
+	"HighFrequencyTrading/sign"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// useServer 启动模拟服务并将所有接口地址指向它
+func useServer(t *testing.T) *Server {
+	t.Helper()
+	s := New()
+	original := endpoint.Get()
+	endpoint.Set(s.Endpoints())
+	t.Cleanup(func() {
+		endpoint.Set(original)
+		s.Close()
+	})
+	return s
+}
+
+func TestLoginIssuesTicket(t *testing.T) {
+	s := useServer(t)
+	s.AddAccount("13800138000", "123456")
+
+	ticket, err := sign.UserLoginNormal("13800138000", "123456")
+	if err != nil {
+		t.Fatalf("登录失败: %v", err)
+	}
+	if !strings.HasPrefix(ticket, "ticket-13800138000-") {
+		t.Errorf("ticket = %q", ticket)
+	}
+	if err := sign.ValidateTicket(ticket); err != nil {
+		t.Errorf("刚签发的 ticket 校验失败: %v", err)
+	}
+	s.ExpireTickets()
+	if err := sign.ValidateTicket(ticket); err == nil {
+		t.Error("失效后校验应失败")
+	}
+
+	if _, err := sign.UserLoginNormal("13800138000", "654321"); err == nil {
+		t.Error("密码错误时应登录失败")
+	}
+}
+
+func TestSMSLogin(t *testing.T) {
+	useServer(t)
+	auth := sign.SMSAuth{Codes: sign.NewPrompter(strings.NewReader(DefaultSMSCode+"\n"), new(strings.Builder))}
+	if _, err := auth.Login("13900139000"); err != nil {
+		t.Fatalf("验证码登录失败: %v", err)
+	}
+}
+
+// exchange 以 phone 的商城登录发起一次兑换，返回状态码与业务码
+func exchange(t *testing.T, s *Server, phone, aid string) (int, string) {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodPost, endpoint.Mall(PathExchange), strings.NewReader(`{"activityId":"`+aid+`"}`))
+	if phone != "" {
+		s.mu.Lock()
+		for token, p := range s.mall {
+			if p == phone {
+				req.Header.Set("Authorization", token)
+			}
+		}
+		s.mu.Unlock()
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var reply struct {
+		Code string `json:"code"`
+	}
+	json.NewDecoder(resp.Body).Decode(&reply)
+	return resp.StatusCode, reply.Code
+}
+
+// mallLogin 用 ticket 登录商城
+func mallLogin(t *testing.T, ticket string) {
+	t.Helper()
+	if err := sign.ValidateTicket(ticket); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExchangeScript(t *testing.T) {
+	s := useServer(t)
+	s.SetStock("aid_5", 1)
+	s.SetPhoneLimit(1)
+	mallLogin(t, s.IssueTicket("13800138000"))
+	mallLogin(t, s.IssueTicket("13900139000"))
+
+	opens := time.Now().Add(time.Hour)
+	s.OpenAt(opens)
+	steps := []struct {
+		name  string
+		phone string
+		aid   string
+		setup func()
+		want  string
+	}{
+		{name: "未登录", aid: "aid_5", want: CodeNotLoggedIn},
+		{name: "活动不存在", phone: "13800138000", aid: "aid_x", want: CodeNoActivity},
+		{name: "未开场", phone: "13800138000", aid: "aid_5", want: CodeNotOpen},
+		{name: "成功", phone: "13800138000", aid: "aid_5", setup: func() { s.OpenAt(time.Time{}) }, want: CodeOK},
+		{name: "单号限购", phone: "13800138000", aid: "aid_5", want: CodeLimited},
+		{name: "已兑完", phone: "13900139000", aid: "aid_5", want: CodeSoldOut},
+	}
+	for _, st := range steps {
+		if st.setup != nil {
+			st.setup()
+		}
+		if status, code := exchange(t, s, st.phone, st.aid); status != http.StatusOK || code != st.want {
+			t.Errorf("%s: status=%d code=%q，期望 %q", st.name, status, code, st.want)
+		}
+	}
+	if got := s.Granted("13800138000", "aid_5"); got != 1 {
+		t.Errorf("Granted = %d", got)
+	}
+	if got := len(s.Exchanges()); got != len(steps) {
+		t.Errorf("记录了 %d 次兑换请求，期望 %d", got, len(steps))
+	}
+}
+
+func TestExchangeFaults(t *testing.T) {
+	s := useServer(t)
+	s.SetStock("aid_5", 10)
+	mallLogin(t, s.IssueTicket("13800138000"))
+	s.InjectFaults(TooManyRequests, BadGateway, Fault{Status: http.StatusOK, Body: Malformed.Body, Latency: 50 * time.Millisecond})
+
+	for _, want := range []int{http.StatusTooManyRequests, http.StatusBadGateway} {
+		if status, _ := exchange(t, s, "13800138000", "aid_5"); status != want {
+			t.Errorf("status = %d，期望 %d", status, want)
+		}
+	}
+	start := time.Now()
+	if status, code := exchange(t, s, "13800138000", "aid_5"); status != http.StatusOK || code != "" {
+		t.Errorf("畸形响应: status=%d code=%q", status, code)
+	}
+	if time.Since(start) < 50*time.Millisecond {
+		t.Error("未注入延迟")
+	}
+	if _, code := exchange(t, s, "13800138000", "aid_5"); code != CodeOK {
+		t.Errorf("异常取完后应恢复正常，code=%q", code)
+	}
+	if s.Stock("aid_5") != 9 {
+		t.Errorf("异常响应不应消耗库存: %d", s.Stock("aid_5"))
+	}
+}
+
+func TestLoginErrors(t *testing.T) {
+	s := useServer(t)
+	s.AddAccount("13800138000", "123456")
+
+	login := func() error {
+		_, err := sign.UserLoginNormal("13800138000", "123456")
+		return err
+	}
+	tests := []struct {
+		name  string
+		setup func()
+		want  error
+	}{
+		{name: "账号锁定", setup: func() { s.RejectLogin("13800138000", ResultLocked, "账号已锁定") }, want: sign.ErrAccountLocked},
+		{name: "需要验证", setup: func() { s.RejectLogin("13800138000", ResultVerify, "请进行短信验证") }, want: sign.ErrVerificationRequired},
+		{name: "系统繁忙", setup: func() { s.RejectLogin("13800138000", ResultBusy, "系统繁忙") }, want: sign.ErrServiceUnavailable},
+		{name: "限流", setup: func() { s.RejectLogin("13800138000", "", ""); s.InjectLoginFaults(TooManyRequests) }, want: sign.ErrServiceUnavailable},
+		{name: "网关错误", setup: func() { s.InjectLoginFaults(BadGateway) }, want: sign.ErrServiceUnavailable},
+		{name: "响应畸形", setup: func() { s.InjectLoginFaults(Malformed) }, want: sign.ErrProtocolChanged},
+	}
+	for _, tt := range tests {
+		tt.setup()
+		err := login()
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: err = %v，期望 %v", tt.name, err, tt.want)
+		}
+	}
+
+	_, err := sign.UserLoginNormal("13800138000", "000000")
+	var le *sign.LoginError
+	if !errors.As(err, &le) || le.Code != ResultWrongPassword || !errors.Is(err, sign.ErrWrongPassword) {
+		t.Errorf("密码错误应保留原始业务码: %#v", err)
+	}
+	if err := login(); err != nil {
+		t.Errorf("异常取完后应恢复正常: %v", err)
+	}
+}
@@ -0,0 +1,239 @@
+package fakect
+
+import (
+	"HighFrequencyTrading/sign"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// 客户端接口的业务码
+const (
+	ResultOK            = "0000"
+	ResultWrongPassword = "8105"
+	ResultBadCode       = "8106"
+	ResultLocked        = "8107"
+	ResultVerify        = "8108"
+	ResultBusy          = "9000"
+	ResultBadRequest    = "9999"
+)
+
+// clientRequest : userLoginNormal / getRandomCode 的请求体
+type clientRequest struct {
+	HeaderInfos struct {
+		Code          string `json:"code"`
+		UserLoginName string `json:"userLoginName"`
+	} `json:"headerInfos"`
+	Content struct {
+		FieldData struct {
+			LoginType      string `json:"loginType"`
+			PhoneNum       string `json:"phoneNum"`
+			Authentication string `json:"authentication"`
+		} `json:"fieldData"`
+	} `json:"content"`
+}
+
+// decodePhone : sign.EncodePhone 的逆运算
+func decodePhone(encoded string) string {
+	rs := []rune(encoded)
+	for i := range rs {
+		rs[i] -= 2
+	}
+	return string(rs)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeResult : 客户端接口统一的 responseData 包装
+func writeResult(w http.ResponseWriter, code, desc string, data interface{}) {
+	writeJSON(w, map[string]interface{}{
+		"responseData": map[string]interface{}{"resultCode": code, "resultDesc": desc, "data": data},
+	})
+}
+
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	var fault *Fault
+	if len(s.loginFail) > 0 {
+		f := s.loginFail[0]
+		s.loginFail = s.loginFail[1:]
+		fault = &f
+	}
+	s.mu.Unlock()
+	if fault != nil {
+		time.Sleep(fault.Latency)
+		w.WriteHeader(fault.Status)
+		io.WriteString(w, fault.Body)
+		return
+	}
+
+	var req clientRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeResult(w, ResultBadRequest, "请求格式错误", nil)
+		return
+	}
+	phone := decodePhone(req.Content.FieldData.PhoneNum)
+	auth := req.Content.FieldData.Authentication
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if rej, ok := s.rejects[phone]; ok {
+		writeResult(w, rej[0], rej[1], nil)
+		return
+	}
+	switch req.Content.FieldData.LoginType {
+	case "4":
+		if pwd, ok := s.passwords[phone]; !ok || pwd != auth {
+			writeResult(w, ResultWrongPassword, "账号或密码错误", nil)
+			return
+		}
+	case "5":
+		if code, ok := s.smsCodes[phone]; !ok || code != auth {
+			writeResult(w, ResultBadCode, "验证码错误", nil)
+			return
+		}
+		delete(s.smsCodes, phone)
+	default:
+		writeResult(w, ResultBadRequest, "不支持的登录方式", nil)
+		return
+	}
+
+	n := s.nextID()
+	userID, token := fmt.Sprintf("U%d", n), fmt.Sprintf("token-%d", n)
+	s.users[userID] = phone
+	s.tokens[token] = phone
+	writeResult(w, ResultOK, "成功", map[string]interface{}{
+		"loginSuccessResult": map[string]interface{}{"userId": userID, "token": token, "phoneNbr": phone},
+	})
+}
+
+func (s *Server) handleSMSCode(w http.ResponseWriter, r *http.Request) {
+	var req clientRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeResult(w, ResultBadRequest, "请求格式错误", nil)
+		return
+	}
+	phone := decodePhone(req.Content.FieldData.PhoneNum)
+	s.mu.Lock()
+	s.smsCodes[phone] = DefaultSMSCode
+	s.mu.Unlock()
+	writeResult(w, ResultOK, "验证码已发送", nil)
+}
+
+// xmlRequest : clientXML 的请求体
+type xmlRequest struct {
+	HeaderInfos struct {
+		Code          string
+		Token         string
+		UserLoginName string
+	}
+	Content struct {
+		FieldData struct {
+			TargetId string
+		}
+	}
+}
+
+// xmlResponse : clientXML 的响应体
+type xmlResponse struct {
+	XMLName      xml.Name `xml:"Response"`
+	ResponseData struct {
+		ResultCode string
+		ResultDesc string
+		Data       *struct {
+			Ticket string
+		} `xml:",omitempty"`
+	}
+}
+
+func writeXML(w http.ResponseWriter, code, desc, ticket string) {
+	var resp xmlResponse
+	resp.ResponseData.ResultCode, resp.ResponseData.ResultDesc = code, desc
+	if ticket != "" {
+		resp.ResponseData.Data = &struct{ Ticket string }{Ticket: ticket}
+	}
+	w.Header().Set("Content-Type", "application/xml;charset=UTF-8")
+	io.WriteString(w, xml.Header)
+	xml.NewEncoder(w).Encode(resp)
+}
+
+func (s *Server) handleClientXML(w http.ResponseWriter, r *http.Request) {
+	var req xmlRequest
+	if err := xml.NewDecoder(r.Body).Decode(&req); err != nil || req.HeaderInfos.Code != "getSingle" {
+		writeXML(w, ResultBadRequest, "请求格式错误", "")
+		return
+	}
+	userID, err := sign.Decrypt(req.Content.FieldData.TargetId)
+	if err != nil {
+		writeXML(w, ResultBadRequest, "TargetId 无法解密", "")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	phone, ok := s.tokens[req.HeaderInfos.Token]
+	if !ok || phone != req.HeaderInfos.UserLoginName || s.users[userID] != phone {
+		writeXML(w, "X201", "登录已失效", "")
+		return
+	}
+	encrypted, err := sign.Encrypt(s.issueTicket(phone))
+	if err != nil {
+		writeXML(w, ResultBadRequest, err.Error(), "")
+		return
+	}
+	writeXML(w, ResultOK, "成功", encrypted)
+}
+
+// issueTicket : 调用方需持有锁
+func (s *Server) issueTicket(phone string) string {
+	ticket := fmt.Sprintf("ticket-%s-%d", phone, s.nextID())
+	s.tickets[ticket] = phone
+	return ticket
+}
+
+// 金豆商城接口的业务码
+const (
+	CodeOK          = "0"
+	CodeNotOpen     = "-1"
+	CodeNotLoggedIn = "-2"
+	CodeNoActivity  = "-3"
+	CodeBadRequest  = "-4"
+	CodeNoBeans     = "-5"
+	CodeSoldOut     = "-6"
+	CodeLimited     = "-7"
+	CodeBadTicket   = "1001"
+)
+
+// SessionCookie 商城登录后下发的会话 Cookie，与 Authorization 请求头二选一即可
+const SessionCookie = "JDMALL_SESSION"
+
+func (s *Server) handleMallLogin(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Ticket string `json:"ticket"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, map[string]string{"code": CodeBadRequest, "msg": "参数错误"})
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	phone, ok := s.tickets[req.Ticket]
+	if !ok {
+		writeJSON(w, map[string]string{"code": CodeBadTicket, "msg": "ticket 无效"})
+		return
+	}
+	token := fmt.Sprintf("mall-%d", s.nextID())
+	s.mall[token] = phone
+	http.SetCookie(w, &http.Cookie{Name: SessionCookie, Value: token, Path: "/"})
+	writeJSON(w, map[string]interface{}{
+		"code": CodeOK,
+		"msg":  "登录成功",
+		"biz":  map[string]string{"token": token, "phone": phone},
+	})
+}
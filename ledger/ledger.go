@@ -0,0 +1,223 @@
+package ledger
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"HighFrequencyTrading/util"
+)
+
+// 兑换结果；拿到响应的记录按 exchange.Outcome 细分（sold_out、not_started 等）
+const (
+	OutcomeSuccess = "success" // 兑换成功
+	OutcomeFailed  = "failed"  // 服务器返回非成功状态（细分之前的旧记录）
+	OutcomeError   = "error"   // 网络错误等，未拿到响应
+)
+
+// Record : 一次兑换尝试
+type Record struct {
+	Time       time.Time `json:"time"`
+	Session    string    `json:"session"`
+	Phone      string    `json:"phone"`
+	Item       string    `json:"item"`
+	ActivityID string    `json:"activityId"`
+	Outcome    string    `json:"outcome"`
+	HTTPStatus int       `json:"httpStatus,omitempty"`
+	Code       string    `json:"code,omitempty"`    // 服务器返回的业务码
+	Message    string    `json:"message,omitempty"` // 服务器返回的提示信息或错误描述
+	LatencyMs  int64     `json:"latencyMs"`
+}
+
+// Month : 记录所属年月，如 "202503"
+func (r Record) Month() string {
+	return r.Time.Format("200601")
+}
+
+// Ledger : 追加写入的 JSONL 兑换账本，每行一条 Record。
+// 进程内由 mu 互斥，进程间通过 <path>.lock 建议锁互斥
+type Ledger struct {
+	path string
+	mu   sync.Mutex
+}
+
+// Open : 打开账本，文件在首次写入时创建
+func Open(path string) *Ledger {
+	return &Ledger{path: path}
+}
+
+// Path : 账本文件路径
+func (l *Ledger) Path() string {
+	return l.path
+}
+
+// Append : 追加一条记录，整行一次写入
+func (l *Ledger) Append(r Record) error {
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	lock, err := util.Lock(l.path)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(line); err != nil {
+		f.Close()
+		return err
+	}
+	// 每条记录落盘后才返回，崩溃最多丢失正在写的一行
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Records : 读取全部记录，跳过无法解析的行（例如崩溃时写了一半的最后一行）
+func (l *Ledger) Records() ([]Record, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	lock, err := util.Lock(l.path)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+
+	f, err := os.Open(l.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var res []Record
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for n := 1; sc.Scan(); n++ {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var r Record
+		if err := json.Unmarshal(sc.Bytes(), &r); err != nil {
+			log.Printf("[Ledger] %s 第 %d 行无法解析，已跳过: %v", l.path, n, err)
+			continue
+		}
+		res = append(res, r)
+	}
+	return res, sc.Err()
+}
+
+// Rewrite : 只保留 keep 返回 true 的记录，原子地重写账本；返回移除的条数
+func (l *Ledger) Rewrite(keep func(Record) bool) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	lock, err := util.Lock(l.path)
+	if err != nil {
+		return 0, err
+	}
+	defer lock.Unlock()
+
+	data, err := os.ReadFile(l.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	var buf bytes.Buffer
+	removed := 0
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		var r Record
+		if len(sc.Bytes()) == 0 || json.Unmarshal(sc.Bytes(), &r) != nil {
+			continue
+		}
+		if !keep(r) {
+			removed++
+			continue
+		}
+		buf.Write(sc.Bytes())
+		buf.WriteByte('\n')
+	}
+	if err := sc.Err(); err != nil {
+		return 0, err
+	}
+	if removed == 0 {
+		return 0, nil
+	}
+	return removed, util.WriteFileAtomic(l.path, buf.Bytes(), 0600)
+}
+
+// MonthView : 成功记录按 年月 -> 话费标题 -> []手机号 汇总（即 Dhjl 结构）
+func MonthView(records []Record) map[string]map[string][]string {
+	res := make(map[string]map[string][]string)
+	for _, r := range records {
+		if r.Outcome != OutcomeSuccess {
+			continue
+		}
+		month := r.Month()
+		if res[month] == nil {
+			res[month] = make(map[string][]string)
+		}
+		res[month][r.Item] = appendUnique(res[month][r.Item], r.Phone)
+	}
+	return res
+}
+
+// PhoneView : 成功记录按 手机号 -> 年月 -> []话费标题 汇总
+func PhoneView(records []Record) map[string]map[string][]string {
+	res := make(map[string]map[string][]string)
+	for _, r := range records {
+		if r.Outcome != OutcomeSuccess {
+			continue
+		}
+		if res[r.Phone] == nil {
+			res[r.Phone] = make(map[string][]string)
+		}
+		month := r.Month()
+		res[r.Phone][month] = appendUnique(res[r.Phone][month], r.Item)
+	}
+	return res
+}
+
+// Filter : 按条件筛选记录，空条件表示不限
+func Filter(records []Record, month, phone, item string) []Record {
+	var res []Record
+	for _, r := range records {
+		if (month == "" || r.Month() == month) &&
+			(phone == "" || r.Phone == phone) &&
+			(item == "" || r.Item == item) {
+			res = append(res, r)
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool { return res[i].Time.Before(res[j].Time) })
+	return res
+}
+
+func appendUnique(arr []string, s string) []string {
+	for _, v := range arr {
+		if v == s {
+			return arr
+		}
+	}
+	return append(arr, s)
+}
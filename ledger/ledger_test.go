@@ -0,0 +1,76 @@
+// ledger_test.go
+package ledger
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAppendAndViews(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.jsonl")
+	l := Open(path)
+
+	march := time.Date(2025, 3, 1, 10, 0, 0, 0, time.Local)
+	april := time.Date(2025, 4, 1, 10, 0, 0, 0, time.Local)
+	records := []Record{
+		{Time: march, Session: "10", Phone: "13800138000", Item: "5元话费", ActivityID: "a5", Outcome: OutcomeFailed, HTTPStatus: 500},
+		{Time: march.Add(time.Second), Session: "10", Phone: "13800138000", Item: "5元话费", ActivityID: "a5", Outcome: OutcomeSuccess, HTTPStatus: 200, LatencyMs: 35},
+		{Time: march.Add(2 * time.Second), Session: "10", Phone: "13800138000", Item: "5元话费", ActivityID: "a5", Outcome: OutcomeSuccess, HTTPStatus: 200},
+		{Time: april, Session: "14", Phone: "13900139000", Item: "10元话费", ActivityID: "a10", Outcome: OutcomeSuccess, HTTPStatus: 200},
+		{Time: april, Session: "14", Phone: "13800138000", Item: "10元话费", ActivityID: "a10", Outcome: OutcomeError, Message: "timeout"},
+	}
+	for _, r := range records {
+		if err := l.Append(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 模拟崩溃时写了一半的最后一行
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"time":"2025-04-0`)
+	f.Close()
+
+	got, err := l.Records()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(records) {
+		t.Fatalf("记录数 = %d, 期望 %d", len(got), len(records))
+	}
+	if got[1].LatencyMs != 35 || got[4].Message != "timeout" {
+		t.Errorf("记录内容不符: %+v", got)
+	}
+
+	months := MonthView(got)
+	if phones := months["202503"]["5元话费"]; len(phones) != 1 || phones[0] != "13800138000" {
+		t.Errorf("202503 月视图不符: %v", months["202503"])
+	}
+	if phones := months["202504"]["10元话费"]; len(phones) != 1 || phones[0] != "13900139000" {
+		t.Errorf("失败记录不应计入月视图: %v", months["202504"])
+	}
+
+	phones := PhoneView(got)
+	if items := phones["13800138000"]["202503"]; len(items) != 1 {
+		t.Errorf("手机号视图不符: %v", phones["13800138000"])
+	}
+	if _, ok := phones["13800138000"]["202504"]; ok {
+		t.Errorf("失败记录不应计入手机号视图: %v", phones["13800138000"])
+	}
+
+	if n := len(Filter(got, "202504", "13800138000", "")); n != 1 {
+		t.Errorf("Filter 结果数 = %d, 期望 1", n)
+	}
+}
+
+func TestRecordsMissingFile(t *testing.T) {
+	l := Open(filepath.Join(t.TempDir(), "none.jsonl"))
+	got, err := l.Records()
+	if err != nil || got != nil {
+		t.Fatalf("文件不存在时应返回空: %v, %v", got, err)
+	}
+}
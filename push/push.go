@@ -1,6 +1,7 @@
 package push
 
 import (
+	"HighFrequencyTrading/endpoint"
 	"bytes"
 	"encoding/json"
 	"errors"
@@ -49,7 +50,7 @@ func Send(content, appToken, uid string) (*Response, error) {
 	}
 
 	resp, err := http.Post(
-		"https://wxpusher.zjiecode.com/api/send/message",
+		endpoint.WxPusher("/api/send/message"),
 		"application/json",
 		bytes.NewBuffer(jsonData),
 	)
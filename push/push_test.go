@@ -2,19 +2,20 @@
 package push
 
 import (
+	"HighFrequencyTrading/endpoint"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
-	"os"
 	"testing"
 )
 
-var sendMessageURL = "https://wxpusher.zjiecode.com/api/send/message"
-
 func TestSendSuccess(t *testing.T) {
 	// 创建一个模拟的 HTTP 测试服务器，模拟 WxPusher API 接口
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// 可选：验证请求体内容
+		if r.URL.Path != "/api/send/message" {
+			t.Errorf("请求路径为 %s", r.URL.Path)
+		}
 		var reqMsg Message
 		if err := json.NewDecoder(r.Body).Decode(&reqMsg); err != nil {
 			t.Errorf("解码请求体失败: %v", err)
@@ -31,15 +32,15 @@ func TestSendSuccess(t *testing.T) {
 	defer ts.Close()
 
 	// 在测试中将接口 URL 覆盖为测试服务器的地址
-	originalURL := sendMessageURL
-	sendMessageURL = ts.URL
-	defer func() { sendMessageURL = originalURL }()
+	original := endpoint.Get()
+	endpoint.Set(endpoint.Endpoints{WxPusher: ts.URL})
+	defer endpoint.Set(original)
 
 	// 设置环境变量（也可以直接传入 appToken 和 uid）
 	appToken := "AT_fg9ETrNBSf0UwqSWTJMU6nCUyIKzrEz0"
 	uid := "UID_FsE6vt9mYWsGi16fASvRC9GZCaCT"
-	os.Setenv("WXPUSHER_APP_TOKEN", appToken)
-	os.Setenv("WXPUSHER_UID", uid)
+	t.Setenv("WXPUSHER_APP_TOKEN", appToken)
+	t.Setenv("WXPUSHER_UID", uid)
 
 	// 测试发送消息
 	content := "测试消息内容"
@@ -0,0 +1,98 @@
+package secret
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// fingerprintPrefix 凭证摘要的前缀，其后为 base64(salt) "$" base64(HMAC-SHA256)
+const fingerprintPrefix = "fp:v1:"
+
+// Fingerprint 用 LoadFingerprintKey 返回的密钥生成凭证的加盐摘要，用于判断凭证是否变化而不保存凭证本身。
+// 密钥与加密口令无关，轮换、新建或未配置口令都不影响匹配；
+// 只拿到摘要而没有密钥文件时无法离线猜测凭证
+func Fingerprint(key []byte, value string) (string, error) {
+	if len(key) == 0 {
+		return "", errors.New("缺少凭证摘要密钥")
+	}
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	sum := fingerprintSum(key, salt, value)
+	return fingerprintPrefix + base64.StdEncoding.EncodeToString(salt) + "$" + base64.StdEncoding.EncodeToString(sum), nil
+}
+
+// MatchFingerprint 判断 value 是否与 Fingerprint 生成的摘要一致，格式错误时视为不一致
+func MatchFingerprint(key []byte, fp, value string) bool {
+	rest, ok := strings.CutPrefix(fp, fingerprintPrefix)
+	if !ok || len(key) == 0 {
+		return false
+	}
+	saltB64, sumB64, ok := strings.Cut(rest, "$")
+	if !ok {
+		return false
+	}
+	salt, err1 := base64.StdEncoding.DecodeString(saltB64)
+	want, err2 := base64.StdEncoding.DecodeString(sumB64)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	return hmac.Equal(fingerprintSum(key, salt, value), want)
+}
+
+// fingerprintSum HMAC-SHA256(key, salt | value)
+func fingerprintSum(key, salt []byte, value string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(salt)
+	mac.Write([]byte(value))
+	return mac.Sum(nil)
+}
+
+// LoadFingerprintKey 读取 path 中的摘要密钥（hex 编码），不存在时生成随机密钥并以 0600 权限保存
+func LoadFingerprintKey(path string) ([]byte, error) {
+	key, err := readFingerprintKey(path)
+	if !errors.Is(err, os.ErrNotExist) {
+		return key, err
+	}
+	key = make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if errors.Is(err, os.ErrExist) {
+		// 另一个进程刚刚生成，使用它的密钥
+		return readFingerprintKey(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	_, err = f.WriteString(hex.EncodeToString(key) + "\n")
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	return key, nil
+}
+
+func readFingerprintKey(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) == 0 {
+		return nil, fmt.Errorf("凭证摘要密钥文件 %s 格式错误", path)
+	}
+	return key, nil
+}
@@ -0,0 +1,192 @@
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// Prefix 加密值的前缀，其后为 base64(salt|nonce|密文)
+	Prefix = "enc:v1:"
+
+	// EnvKey 直接提供口令的环境变量
+	EnvKey = "TELECOM_SECRET_KEY"
+	// EnvKeyFile 指定密钥文件路径的环境变量
+	EnvKeyFile = "TELECOM_SECRET_KEY_FILE"
+
+	saltSize = 16
+	keySize  = 32
+)
+
+// ErrNoKey 存在加密数据但未配置口令
+var ErrNoKey = errors.New("未配置加密口令，请设置 " + EnvKey + " 或 " + EnvKeyFile)
+
+// Box 使用口令派生的 AES-GCM 密钥加解密单个值
+type Box struct {
+	passphrase []byte
+
+	mu       sync.Mutex
+	keys     map[string][]byte // salt -> 派生密钥，避免重复执行 scrypt
+	sealSalt []byte            // 本实例加密时复用的 salt，nonce 仍逐次随机
+}
+
+// NewBox 根据口令创建 Box
+func NewBox(passphrase string) *Box {
+	return &Box{
+		passphrase: []byte(passphrase),
+		keys:       make(map[string][]byte),
+	}
+}
+
+// Equal 两个 Box 是否使用同一口令，均为 nil 时视为相同
+func (b *Box) Equal(o *Box) bool {
+	if b == nil || o == nil {
+		return b == o
+	}
+	return subtle.ConstantTimeCompare(b.passphrase, o.passphrase) == 1
+}
+
+// IsSealed 判断值是否为加密格式
+func IsSealed(value string) bool {
+	return strings.HasPrefix(value, Prefix)
+}
+
+// Seal 加密明文，每个值使用独立的随机 nonce
+func (b *Box) Seal(plain string) (string, error) {
+	salt, err := b.salt()
+	if err != nil {
+		return "", err
+	}
+	gcm, err := b.aead(salt)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	out := append(append([]byte(nil), salt...), nonce...)
+	out = gcm.Seal(out, nonce, []byte(plain), nil)
+	return Prefix + base64.StdEncoding.EncodeToString(out), nil
+}
+
+// Open 解密 Seal 生成的值
+func (b *Box) Open(value string) (string, error) {
+	if !IsSealed(value) {
+		return "", errors.New("不是加密值")
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, Prefix))
+	if err != nil {
+		return "", fmt.Errorf("加密值格式错误: %w", err)
+	}
+	if len(raw) < saltSize {
+		return "", errors.New("加密值过短")
+	}
+	gcm, err := b.aead(raw[:saltSize])
+	if err != nil {
+		return "", err
+	}
+	raw = raw[saltSize:]
+	if len(raw) < gcm.NonceSize() {
+		return "", errors.New("加密值过短")
+	}
+	plain, err := gcm.Open(nil, raw[:gcm.NonceSize()], raw[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("解密失败，口令错误或数据已损坏")
+	}
+	return string(plain), nil
+}
+
+// Reveal 对加密值解密，明文值原样返回；box 为 nil 且值已加密时返回 ErrNoKey
+func Reveal(b *Box, value string) (string, error) {
+	if !IsSealed(value) {
+		return value, nil
+	}
+	if b == nil {
+		return "", ErrNoKey
+	}
+	return b.Open(value)
+}
+
+func (b *Box) salt() ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.sealSalt == nil {
+		salt := make([]byte, saltSize)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+		b.sealSalt = salt
+	}
+	return b.sealSalt, nil
+}
+
+func (b *Box) aead(salt []byte) (cipher.AEAD, error) {
+	b.mu.Lock()
+	key, ok := b.keys[string(salt)]
+	if !ok {
+		derived, err := scrypt.Key(b.passphrase, salt, 1<<15, 8, 1, keySize)
+		if err != nil {
+			b.mu.Unlock()
+			return nil, err
+		}
+		key = derived
+		b.keys[string(salt)] = key
+	}
+	b.mu.Unlock()
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// GenerateKey 生成随机口令（hex 编码）
+func GenerateKey() (string, error) {
+	buf := make([]byte, keySize)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// KeyFilePath 返回密钥文件路径：环境变量优先，否则使用 defaultPath
+func KeyFilePath(defaultPath string) string {
+	if p := os.Getenv(EnvKeyFile); p != "" {
+		return p
+	}
+	return defaultPath
+}
+
+// Load 按优先级加载口令：环境变量 TELECOM_SECRET_KEY → 密钥文件；
+// 均未配置时返回 nil，表示以明文方式运行
+func Load(defaultKeyFile string) (*Box, error) {
+	if pass := os.Getenv(EnvKey); pass != "" {
+		return NewBox(pass), nil
+	}
+	data, err := os.ReadFile(KeyFilePath(defaultKeyFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	pass := strings.TrimSpace(string(data))
+	if pass == "" {
+		return nil, fmt.Errorf("密钥文件 %s 为空", KeyFilePath(defaultKeyFile))
+	}
+	return NewBox(pass), nil
+}
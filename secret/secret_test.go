@@ -0,0 +1,132 @@
+// secret_test.go
+package secret
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSealOpen(t *testing.T) {
+	box := NewBox("correct horse")
+	sealed, err := box.Seal("p#ss&word")
+	if err != nil {
+		t.Fatalf("Seal 返回错误: %v", err)
+	}
+	if !IsSealed(sealed) {
+		t.Fatalf("加密值缺少前缀: %s", sealed)
+	}
+
+	plain, err := NewBox("correct horse").Open(sealed)
+	if err != nil || plain != "p#ss&word" {
+		t.Fatalf("Open = %q, %v", plain, err)
+	}
+	if _, err := NewBox("wrong").Open(sealed); err == nil {
+		t.Error("错误口令应解密失败")
+	}
+}
+
+func TestReveal(t *testing.T) {
+	if v, err := Reveal(nil, "plain"); err != nil || v != "plain" {
+		t.Errorf("明文应原样返回: %q, %v", v, err)
+	}
+	sealed, _ := NewBox("k").Seal("x")
+	if _, err := Reveal(nil, sealed); err != ErrNoKey {
+		t.Errorf("未配置口令时应返回 ErrNoKey，实际 %v", err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "telecom.key")
+	t.Setenv(EnvKey, "")
+	t.Setenv(EnvKeyFile, "")
+
+	box, err := Load(keyFile)
+	if err != nil || box != nil {
+		t.Fatalf("无密钥文件时应返回 nil: %v, %v", box, err)
+	}
+
+	if err := os.WriteFile(keyFile, []byte("file-key\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	sealed, _ := NewBox("file-key").Seal("ticket")
+	box, err = Load(keyFile)
+	if err != nil || box == nil {
+		t.Fatalf("Load 返回错误: %v", err)
+	}
+	if v, err := box.Open(sealed); err != nil || v != "ticket" {
+		t.Errorf("密钥文件口令不符: %q, %v", v, err)
+	}
+
+	// 环境变量优先于密钥文件
+	t.Setenv(EnvKey, "env-key")
+	box, _ = Load(keyFile)
+	if _, err := box.Open(sealed); err == nil {
+		t.Error("应使用环境变量中的口令")
+	}
+}
+
+func TestVaultReseal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.json")
+	oldBox, newBox := NewBox("old"), NewBox("new")
+
+	v, _ := LoadVault(path)
+	if err := v.SetPassword(oldBox, "13800138000", "123456"); err != nil {
+		t.Fatal(err)
+	}
+	v.Passwords["13900139000"] = "plain-pwd"
+	if err := v.Reseal(oldBox, newBox); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadVault(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for phone, want := range map[string]string{"13800138000": "123456", "13900139000": "plain-pwd"} {
+		got, ok, err := loaded.Password(newBox, phone)
+		if !ok || err != nil || got != want {
+			t.Errorf("%s: got %q, %v, %v", phone, got, ok, err)
+		}
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fingerprint.key")
+	key, err := LoadFingerprintKey(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, err := LoadFingerprintKey(path); err != nil || string(again) != string(key) {
+		t.Fatalf("再次加载应得到同一密钥: %v", err)
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0600 {
+		t.Errorf("密钥文件权限应为 0600: %v, %v", fi, err)
+	}
+
+	fp, err := Fingerprint(key, "password\x00123456")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !MatchFingerprint(key, fp, "password\x00123456") {
+		t.Fatalf("摘要应与原凭证匹配: %s", fp)
+	}
+	if MatchFingerprint(key, fp, "password\x00654321") {
+		t.Error("凭证修改后不应匹配")
+	}
+	// 没有密钥文件时无法由摘要验证凭证
+	other, err := LoadFingerprintKey(filepath.Join(t.TempDir(), "fingerprint.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if MatchFingerprint(other, fp, "password\x00123456") || MatchFingerprint(nil, fp, "password\x00123456") {
+		t.Error("密钥不同时不应匹配")
+	}
+	if _, err := Fingerprint(nil, "123456"); err == nil {
+		t.Error("没有密钥时不应生成摘要")
+	}
+}
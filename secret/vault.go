@@ -0,0 +1,92 @@
+package secret
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"HighFrequencyTrading/util"
+)
+
+// Vault 加密存储的账号密码：手机号 -> 加密后的密码
+type Vault struct {
+	Passwords map[string]string `json:"passwords"`
+
+	path string
+}
+
+// LoadVault 读取保险库文件，不存在时返回空保险库
+func LoadVault(path string) (*Vault, error) {
+	v := &Vault{Passwords: make(map[string]string), path: path}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return v, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, fmt.Errorf("解析 %s 失败: %w", path, err)
+	}
+	if v.Passwords == nil {
+		v.Passwords = make(map[string]string)
+	}
+	return v, nil
+}
+
+// Password 读取并解密指定手机号的密码
+func (v *Vault) Password(b *Box, phone string) (string, bool, error) {
+	sealed, ok := v.Passwords[phone]
+	if !ok {
+		return "", false, nil
+	}
+	plain, err := Reveal(b, sealed)
+	return plain, true, err
+}
+
+// SetPassword 加密并保存密码
+func (v *Vault) SetPassword(b *Box, phone, password string) error {
+	if b == nil {
+		return ErrNoKey
+	}
+	sealed, err := b.Seal(password)
+	if err != nil {
+		return err
+	}
+	v.Passwords[phone] = sealed
+	return nil
+}
+
+// Reseal 用 newBox 重新加密所有密码
+func (v *Vault) Reseal(oldBox, newBox *Box) error {
+	for phone, sealed := range v.Passwords {
+		plain, err := Reveal(oldBox, sealed)
+		if err != nil {
+			return fmt.Errorf("解密 %s 的密码失败: %w", phone, err)
+		}
+		if v.Passwords[phone], err = newBox.Seal(plain); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Save 原子地写回保险库文件
+func (v *Vault) Save() error {
+	p, err := v.Stage()
+	if err != nil {
+		return err
+	}
+	defer p.Discard()
+	return p.Commit()
+}
+
+// Stage 把保险库写入临时文件，Commit 后才替换保险库文件
+func (v *Vault) Stage() (*util.PendingFile, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return util.StageFile(v.path, data, 0600)
+}
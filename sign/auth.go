@@ -0,0 +1,105 @@
+package sign
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// 登录方式
+const (
+	AuthPassword = "password" // 服务密码（默认）
+	AuthSMS      = "sms"      // 短信验证码
+	AuthTicket   = "ticket"   // 导入已有的 ticket
+)
+
+// AuthMethods 支持的登录方式
+var AuthMethods = []string{AuthPassword, AuthSMS, AuthTicket}
+
+// Authenticator 登录方式，Login 成功时返回 ticket
+type Authenticator interface {
+	Method() string
+	Login(phone string) (string, error)
+}
+
+// PasswordAuth 服务密码登录
+type PasswordAuth struct {
+	Password string
+}
+
+func (a PasswordAuth) Method() string { return AuthPassword }
+
+func (a PasswordAuth) Login(phone string) (string, error) {
+	return UserLoginNormal(phone, a.Password)
+}
+
+// CodeSource 提供收到的短信验证码
+type CodeSource interface {
+	Code(phone string) (string, error)
+}
+
+// SMSAuth 短信验证码登录：先请求验证码，再从 Codes 读取后提交
+type SMSAuth struct {
+	Codes CodeSource
+}
+
+func (a SMSAuth) Method() string { return AuthSMS }
+
+func (a SMSAuth) Login(phone string) (string, error) {
+	if a.Codes == nil {
+		return "", errors.New("未提供验证码输入")
+	}
+	if err := RequestSMSCode(phone); err != nil {
+		return "", err
+	}
+	code, err := a.Codes.Code(phone)
+	if err != nil {
+		return "", err
+	}
+	return UserLoginSMS(phone, code)
+}
+
+// TicketAuth 直接使用用户导入的 ticket，失效后需重新导入
+type TicketAuth struct {
+	Ticket string
+}
+
+func (a TicketAuth) Method() string { return AuthTicket }
+
+func (a TicketAuth) Login(phone string) (string, error) {
+	if a.Ticket == "" {
+		return "", fmt.Errorf("%s 未配置 ticket", phone)
+	}
+	return a.Ticket, nil
+}
+
+// Prompter 从交互终端或标准输入逐行读取验证码。
+// 多个账号并发登录时依次提示，避免输入串行错位
+type Prompter struct {
+	mu  sync.Mutex
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// NewPrompter 在 out 上输出提示，从 in 读取
+func NewPrompter(in io.Reader, out io.Writer) *Prompter {
+	return &Prompter{in: bufio.NewReader(in), out: out}
+}
+
+func (p *Prompter) Code(phone string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fmt.Fprintf(p.out, "请输入 %s 收到的短信验证码: ", phone)
+	line, err := p.in.ReadString('\n')
+	line = strings.TrimSpace(line)
+	if line == "" {
+		if err == nil || errors.Is(err, io.EOF) {
+			err = errors.New("未读取到验证码")
+		}
+		return "", &LoginError{Kind: ErrInvalidInput, Err: fmt.Errorf("%s: %w", phone, err)}
+	}
+	return line, nil
+}
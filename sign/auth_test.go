@@ -0,0 +1,44 @@
+package sign
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPrompterReadsLines(t *testing.T) {
+	var out bytes.Buffer
+	p := NewPrompter(strings.NewReader("123456\n 654321 \n"), &out)
+	for _, want := range []string{"123456", "654321"} {
+		got, err := p.Code("13800138000")
+		if err != nil || got != want {
+			t.Fatalf("Code() = %q, %v，期望 %q", got, err, want)
+		}
+	}
+	if _, err := p.Code("13800138000"); err == nil {
+		t.Fatal("输入耗尽时应返回错误")
+	}
+	if !strings.Contains(out.String(), "13800138000") {
+		t.Errorf("提示中应包含手机号: %q", out.String())
+	}
+}
+
+func TestUserLoginSMSRejectsMalformedCode(t *testing.T) {
+	_, err := UserLoginSMS("13800138000", "12ab")
+	if !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("格式不正确的验证码应报 ErrInvalidInput: %v", err)
+	}
+	if Retryable(err) || errors.Is(err, ErrWrongPassword) {
+		t.Errorf("输入错误不应重试，也不应视为凭证错误: %v", err)
+	}
+}
+
+func TestTicketAuth(t *testing.T) {
+	if got, err := (TicketAuth{Ticket: "T1"}).Login("13800138000"); err != nil || got != "T1" {
+		t.Fatalf("Login() = %q, %v", got, err)
+	}
+	if _, err := (TicketAuth{}).Login("13800138000"); err == nil {
+		t.Fatal("未配置 ticket 时应返回错误")
+	}
+}
@@ -0,0 +1,121 @@
+package sign
+
+import (
+	"HighFrequencyTrading/endpoint"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// XMLHeaderInfos clientXML 接口的公共请求头
+type XMLHeaderInfos struct {
+	Code           string
+	Timestamp      string
+	BroadAccount   string
+	BroadToken     string
+	ClientType     string
+	ShopId         string
+	Source         string
+	SourcePassword string
+	Token          string
+	UserLoginName  string
+}
+
+// XMLRequest clientXML 的请求信封，FieldData 为各操作自己的字段结构体
+type XMLRequest struct {
+	XMLName     xml.Name `xml:"Request"`
+	HeaderInfos XMLHeaderInfos
+	Content     struct {
+		Attach    string
+		FieldData interface{}
+	}
+}
+
+// XMLResponse clientXML 的响应信封，Data 保留原始 XML，由各操作解码
+type XMLResponse struct {
+	XMLName      xml.Name `xml:"Response"`
+	ResponseData struct {
+		ResultCode string
+		ResultDesc string
+		Data       struct {
+			Inner []byte `xml:",innerxml"`
+		}
+	}
+}
+
+// NewXMLRequest 按当前协议 profile 填好请求头
+func NewXMLRequest(code, phone, token string, fieldData interface{}) *XMLRequest {
+	proto := CurrentProtocol()
+	req := &XMLRequest{HeaderInfos: XMLHeaderInfos{
+		Code:           code,
+		Timestamp:      time.Now().Format("20060102150405"),
+		ClientType:     proto.ClientType,
+		ShopId:         proto.ShopID,
+		Source:         proto.Source,
+		SourcePassword: proto.SourcePassword,
+		Token:          token,
+		UserLoginName:  phone,
+	}}
+	req.Content.Attach = "test"
+	req.Content.FieldData = fieldData
+	return req
+}
+
+// DecodeData 将 ResponseData.Data 解码到 v
+func (r *XMLResponse) DecodeData(v interface{}) error {
+	var buf bytes.Buffer
+	buf.WriteString("<Data>")
+	buf.Write(r.ResponseData.Data.Inner)
+	buf.WriteString("</Data>")
+	return xml.Unmarshal(buf.Bytes(), v)
+}
+
+// CallClientXML 发送 clientXML 请求，ResultCode 不为 0000 时返回 *LoginError；
+// data 不为 nil 时将 ResponseData.Data 解码到 data
+func CallClientXML(req *XMLRequest, data interface{}) error {
+	body, err := xml.Marshal(req)
+	if err != nil {
+		return err
+	}
+	httpReq, err := http.NewRequest("POST", endpoint.Ticket("/map/clientXML"), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/xml")
+	httpReq.Header.Set("User-Agent", CurrentProtocol().AppUserAgent)
+
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return &LoginError{Kind: ErrServiceUnavailable, Err: fmt.Errorf("%s 请求失败: %w", req.HeaderInfos.Code, err)}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return statusError(resp.StatusCode)
+	}
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return &LoginError{Kind: ErrServiceUnavailable, Status: resp.StatusCode, Err: err}
+	}
+
+	var reply XMLResponse
+	if err := xml.Unmarshal(raw, &reply); err != nil {
+		return protocolError("%s 响应不是预期的 XML: %v", req.HeaderInfos.Code, err)
+	}
+	switch code := reply.ResponseData.ResultCode; code {
+	case "0000":
+	case "":
+		return protocolError("%s 响应缺少 ResultCode", req.HeaderInfos.Code)
+	default:
+		return resultError(code, reply.ResponseData.ResultDesc)
+	}
+	if data == nil {
+		return nil
+	}
+	if err := reply.DecodeData(data); err != nil {
+		return protocolError("%s 响应 Data 解析失败: %v", req.HeaderInfos.Code, err)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package sign
+
+import (
+	"HighFrequencyTrading/endpoint"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGetTicketXML(t *testing.T) {
+	var reply string
+	var got struct {
+		HeaderInfos XMLHeaderInfos
+		Content     struct {
+			FieldData getSingleFields
+		}
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := xml.Unmarshal(body, &got); err != nil {
+			t.Errorf("请求不是合法的 XML: %v\n%s", err, body)
+		}
+		io.WriteString(w, reply)
+	}))
+	defer ts.Close()
+	original := endpoint.Get()
+	endpoint.Set(endpoint.Endpoints{Ticket: ts.URL})
+	defer endpoint.Set(original)
+
+	encrypted, err := Encrypt("ticket-abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply = `<?xml version="1.0"?><Response><ResponseData><ResultCode>0000</ResultCode>` +
+		`<Data><Ticket>` + encrypted + `</Ticket></Data></ResponseData></Response>`
+	ticket, err := GetTicket("13800138000", "U1", "tok<&>")
+	if err != nil || ticket != "ticket-abc" {
+		t.Fatalf("GetTicket = %q, %v", ticket, err)
+	}
+	if got.HeaderInfos.Token != "tok<&>" || got.HeaderInfos.Code != "getSingle" || got.Content.FieldData.Url != CurrentProtocol().TicketURL {
+		t.Errorf("请求字段不符: %+v", got)
+	}
+
+	tests := []struct {
+		name  string
+		reply string
+		want  error
+	}{
+		{"业务码", `<Response><ResponseData><ResultCode>8107</ResultCode><ResultDesc>账号已锁定</ResultDesc></ResponseData></Response>`, ErrAccountLocked},
+		{"缺少 Ticket", `<Response><ResponseData><ResultCode>0000</ResultCode><Data/></ResponseData></Response>`, ErrProtocolChanged},
+		{"不是 XML", `{"code":0}`, ErrProtocolChanged},
+	}
+	for _, tt := range tests {
+		reply = tt.reply
+		_, err := GetTicket("13800138000", "U1", "tok")
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: err = %v，期望 %v", tt.name, err, tt.want)
+		}
+	}
+	var le *LoginError
+	reply = tests[0].reply
+	if _, err := GetTicket("13800138000", "U1", "tok"); !errors.As(err, &le) || le.Code != "8107" || !strings.Contains(le.Desc, "锁定") {
+		t.Errorf("应保留 ResultCode 与 ResultDesc: %#v", err)
+	}
+}
@@ -0,0 +1,124 @@
+package sign
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// 登录失败的类别，用 errors.Is 判断；具体的业务码与提示见 *LoginError
+var (
+	ErrWrongPassword        = errors.New("账号、密码或验证码错误")
+	ErrAccountLocked        = errors.New("账号已被锁定")
+	ErrVerificationRequired = errors.New("需要短信或人工验证")
+	ErrServiceUnavailable   = errors.New("登录服务暂不可用")
+	ErrProtocolChanged      = errors.New("登录接口响应格式已变化")
+	ErrInvalidCredential    = errors.New("配置的凭证格式不正确") // 本地检查未通过，请求未发出
+	ErrInvalidInput         = errors.New("输入的验证码无效")   // 未读取到或格式不正确，请求未发出
+)
+
+// resultKinds 已知的 resultCode
+var resultKinds = map[string]error{
+	"8105": ErrWrongPassword,
+	"8106": ErrWrongPassword,
+	"8107": ErrAccountLocked,
+	"8108": ErrVerificationRequired,
+	"9000": ErrServiceUnavailable,
+}
+
+// descKinds 未知 resultCode 时按提示文字归类，按顺序匹配
+var descKinds = []struct {
+	keyword string
+	kind    error
+}{
+	{"锁定", ErrAccountLocked},
+	{"冻结", ErrAccountLocked},
+	{"验证码错误", ErrWrongPassword},
+	{"密码", ErrWrongPassword},
+	{"验证", ErrVerificationRequired},
+	{"繁忙", ErrServiceUnavailable},
+	{"稍后", ErrServiceUnavailable},
+}
+
+// LoginError 登录失败，保留网关返回的原始业务码与提示
+type LoginError struct {
+	Kind   error  // 上面的类别之一，无法归类时为 nil
+	Code   string // responseData.resultCode / ResultCode
+	Desc   string // responseData.resultDesc / ResultDesc
+	Status int    // HTTP 状态码，未收到响应时为 0
+	Err    error  // 底层错误，如网络错误
+}
+
+func (e *LoginError) Error() string {
+	var b strings.Builder
+	if e.Kind != nil {
+		b.WriteString(e.Kind.Error())
+	} else {
+		b.WriteString("登录失败")
+	}
+	if e.Code != "" || e.Desc != "" {
+		fmt.Fprintf(&b, ": code=%s %s", e.Code, e.Desc)
+	}
+	if e.Status != 0 && e.Status != http.StatusOK {
+		fmt.Fprintf(&b, " (HTTP %d)", e.Status)
+	}
+	if e.Err != nil {
+		fmt.Fprintf(&b, ": %v", e.Err)
+	}
+	return b.String()
+}
+
+func (e *LoginError) Unwrap() []error {
+	var errs []error
+	if e.Kind != nil {
+		errs = append(errs, e.Kind)
+	}
+	if e.Err != nil {
+		errs = append(errs, e.Err)
+	}
+	return errs
+}
+
+// resultError 按业务码与提示归类
+func resultError(code, desc string) *LoginError {
+	kind, ok := resultKinds[code]
+	if !ok {
+		for _, k := range descKinds {
+			if strings.Contains(desc, k.keyword) {
+				kind = k.kind
+				break
+			}
+		}
+	}
+	return &LoginError{Kind: kind, Code: code, Desc: desc, Status: http.StatusOK}
+}
+
+// statusError 非 200 的 HTTP 响应：限流与服务端错误可重试，其余视为接口已变化
+func statusError(status int) *LoginError {
+	if status == http.StatusTooManyRequests || status >= 500 {
+		return &LoginError{Kind: ErrServiceUnavailable, Status: status}
+	}
+	return &LoginError{Kind: ErrProtocolChanged, Status: status}
+}
+
+// protocolError 响应无法按预期解析
+func protocolError(format string, args ...interface{}) *LoginError {
+	return &LoginError{Kind: ErrProtocolChanged, Status: http.StatusOK, Err: fmt.Errorf(format, args...)}
+}
+
+// Retryable 稍后重试可能成功的登录错误，即归为 ErrServiceUnavailable 的：
+// 限流、服务端错误与网关提示繁忙；发送请求时的网络错误在调用处已归为该类
+func Retryable(err error) bool {
+	return errors.Is(err, ErrServiceUnavailable)
+}
+
+// NeedsAttention 需要人工处理的登录错误：凭证错误或格式不正确、账号锁定、需要验证或接口变化
+func NeedsAttention(err error) bool {
+	for _, kind := range []error{ErrWrongPassword, ErrInvalidCredential, ErrAccountLocked, ErrVerificationRequired, ErrProtocolChanged} {
+		if errors.Is(err, kind) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package sign
+
+import (
+	"HighFrequencyTrading/endpoint"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrTicketInvalid ticket 已失效，需要重新登录
+var ErrTicketInvalid = errors.New("ticket 已失效")
+
+// ValidateTicket 用 ticket 登录金豆商城，以此轻量确认 ticket 仍然有效。
+// 服务器明确拒绝时返回包装了 ErrTicketInvalid 的错误；网络等其他错误无法判断有效性，原样返回
+func ValidateTicket(ticket string) error {
+	_, err := MallLogin(&http.Client{Timeout: 5 * time.Second}, ticket)
+	return err
+}
+
+// MallLoginPath 金豆商城登录接口
+const MallLoginPath = "/unified/user/login"
+
+// MallLogin 用 ticket 登录金豆商城，返回商城的登录 token（响应中没有时为空）；
+// 商城下发的 Cookie 保存在 client 的 Jar 中。错误的含义同 ValidateTicket
+func MallLogin(client *http.Client, ticket string) (string, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"ticket":       ticket,
+		"backUrl":      url.QueryEscape(endpoint.Get().Mall),
+		"platformCode": "P201010301",
+		"loginType":    2,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequest("POST", endpoint.Mall(MallLoginPath), strings.NewReader(string(body)))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json;charset=UTF-8")
+	req.Header.Set("User-Agent", CurrentProtocol().WebUserAgent)
+	req.Header.Set("Referer", MallReferer())
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("商城登录请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("商城登录请求失败: HTTP %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return "", err
+	}
+	var reply struct {
+		Code json.Number `json:"code"`
+		Msg  string      `json:"msg"`
+		Biz  struct {
+			Token string `json:"token"`
+		} `json:"biz"`
+	}
+	if err := json.Unmarshal(data, &reply); err != nil {
+		return "", fmt.Errorf("商城登录响应无法解析: %v", err)
+	}
+	if reply.Code.String() != "0" {
+		return "", fmt.Errorf("%w: code=%s %s", ErrTicketInvalid, reply.Code, reply.Msg)
+	}
+	return reply.Biz.Token, nil
+}
@@ -0,0 +1,44 @@
+package sign
+
+import (
+	"HighFrequencyTrading/endpoint"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestValidateTicket(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/unified/user/login" {
+			http.NotFound(w, r)
+			return
+		}
+		var req struct {
+			Ticket string `json:"ticket"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Ticket == "GOOD" {
+			w.Write([]byte(`{"code":0,"msg":"ok"}`))
+			return
+		}
+		w.Write([]byte(`{"code":"1001","msg":"ticket invalid"}`))
+	}))
+	defer ts.Close()
+	original := endpoint.Get()
+	endpoint.Set(endpoint.Endpoints{Mall: ts.URL})
+	defer endpoint.Set(original)
+
+	if err := ValidateTicket("GOOD"); err != nil {
+		t.Fatalf("有效 ticket 返回错误: %v", err)
+	}
+	if err := ValidateTicket("DEAD"); !errors.Is(err, ErrTicketInvalid) {
+		t.Fatalf("失效 ticket 应返回 ErrTicketInvalid: %v", err)
+	}
+
+	endpoint.Set(endpoint.Endpoints{Mall: ts.URL + "/missing"})
+	if err := ValidateTicket("GOOD"); err == nil || errors.Is(err, ErrTicketInvalid) {
+		t.Fatalf("HTTP 错误不应判定为 ticket 失效: %v", err)
+	}
+}
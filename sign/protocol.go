@@ -0,0 +1,219 @@
+package sign
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"crypto/x509"
+	_ "embed"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed protocols.yaml
+var embeddedProtocols []byte
+
+// Protocol 一个版本的客户端协议常量，官方 App 更新时随之变化
+type Protocol struct {
+	Extends         string `yaml:"extends,omitempty"`         // 继承的 profile，只需写出变化的字段
+	ClientType      string `yaml:"clientType,omitempty"`      // 请求头 clientType
+	ShopID          string `yaml:"shopId,omitempty"`          // 请求头 shopId
+	Source          string `yaml:"source,omitempty"`          // 请求头 source
+	SourcePassword  string `yaml:"sourcePassword,omitempty"`  // 请求头 sourcePassword
+	DES3Key         string `yaml:"des3Key,omitempty"`         // 加解密 userId 与 ticket 的 3DES 密钥，24 字节
+	PublicKey       string `yaml:"publicKey,omitempty"`       // 加密 loginAuth 的 RSA 公钥 (PEM)
+	TicketURL       string `yaml:"ticketUrl,omitempty"`       // getSingle 请求中的 <Url>
+	LoginAuthPrefix string `yaml:"loginAuthPrefix,omitempty"` // loginAuth 明文的设备前缀
+	SystemVersion   string `yaml:"systemVersion,omitempty"`   // 登录请求中的 systemVersion
+	WebUserAgent    string `yaml:"webUserAgent,omitempty"`    // JSON 接口与金豆商城的 User-Agent
+	AppUserAgent    string `yaml:"appUserAgent,omitempty"`    // clientXML 接口的 User-Agent
+
+	Name string `yaml:"-"` // profile 名称
+}
+
+// ProtocolSet 协议 profile 集合，对应 protocols.yaml
+type ProtocolSet struct {
+	Default  string              `yaml:"default"`
+	Profiles map[string]Protocol `yaml:"profiles"`
+}
+
+// DefaultProtocols 内置的协议 profile
+func DefaultProtocols() (ProtocolSet, error) {
+	return parseProtocols(embeddedProtocols, "内置 protocols.yaml")
+}
+
+// LoadProtocols 内置 profile 与 file 中的覆盖合并：同名 profile 逐字段覆盖，新名称直接加入；
+// file 为空时只使用内置 profile
+func LoadProtocols(file string) (ProtocolSet, error) {
+	set, err := DefaultProtocols()
+	if err != nil || file == "" {
+		return set, err
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return set, err
+	}
+	extra, err := parseProtocols(data, file)
+	if err != nil {
+		return set, err
+	}
+	if extra.Default != "" {
+		set.Default = extra.Default
+	}
+	for name, p := range extra.Profiles {
+		set.Profiles[name] = set.Profiles[name].merge(p)
+	}
+	return set, nil
+}
+
+func parseProtocols(data []byte, from string) (ProtocolSet, error) {
+	var set ProtocolSet
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&set); err != nil {
+		return set, fmt.Errorf("解析 %s 失败: %w", from, err)
+	}
+	if set.Profiles == nil {
+		set.Profiles = make(map[string]Protocol)
+	}
+	return set, nil
+}
+
+// Names 全部 profile 名称，已排序
+func (s ProtocolSet) Names() []string {
+	names := make([]string, 0, len(s.Profiles))
+	for name := range s.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Resolve 展开继承并校验，name 为空时使用 Default
+func (s ProtocolSet) Resolve(name string) (Protocol, error) {
+	if name == "" {
+		name = s.Default
+	}
+	var chain []string
+	p, ok := s.Profiles[name]
+	if !ok {
+		return Protocol{}, fmt.Errorf("协议 profile %q 不存在，可选: %s", name, strings.Join(s.Names(), " / "))
+	}
+	chain = append(chain, name)
+	for p.Extends != "" {
+		parent, ok := s.Profiles[p.Extends]
+		if !ok {
+			return Protocol{}, fmt.Errorf("协议 profile %q 继承的 %q 不存在", chain[len(chain)-1], p.Extends)
+		}
+		for _, seen := range chain {
+			if seen == p.Extends {
+				return Protocol{}, fmt.Errorf("协议 profile 循环继承: %s -> %s", strings.Join(chain, " -> "), p.Extends)
+			}
+		}
+		chain = append(chain, p.Extends)
+		child := p
+		child.Extends = ""
+		p = parent.merge(child)
+	}
+	p.Name = name
+	return p, p.Validate()
+}
+
+// merge 以 o 中的非空字段覆盖 p
+func (p Protocol) merge(o Protocol) Protocol {
+	set := func(dst *string, v string) {
+		if v != "" {
+			*dst = v
+		}
+	}
+	set(&p.Extends, o.Extends)
+	set(&p.ClientType, o.ClientType)
+	set(&p.ShopID, o.ShopID)
+	set(&p.Source, o.Source)
+	set(&p.SourcePassword, o.SourcePassword)
+	set(&p.DES3Key, o.DES3Key)
+	set(&p.PublicKey, o.PublicKey)
+	set(&p.TicketURL, o.TicketURL)
+	set(&p.LoginAuthPrefix, o.LoginAuthPrefix)
+	set(&p.SystemVersion, o.SystemVersion)
+	set(&p.WebUserAgent, o.WebUserAgent)
+	set(&p.AppUserAgent, o.AppUserAgent)
+	return p
+}
+
+// Validate 检查必填字段、3DES 密钥长度与公钥格式
+func (p Protocol) Validate() error {
+	var errs []error
+	for field, v := range map[string]string{
+		"clientType": p.ClientType, "shopId": p.ShopID, "source": p.Source, "sourcePassword": p.SourcePassword,
+		"ticketUrl": p.TicketURL, "loginAuthPrefix": p.LoginAuthPrefix, "systemVersion": p.SystemVersion,
+		"webUserAgent": p.WebUserAgent, "appUserAgent": p.AppUserAgent,
+	} {
+		if v == "" {
+			errs = append(errs, fmt.Errorf("%s 不能为空", field))
+		}
+	}
+	if len(p.DES3Key) != 24 {
+		errs = append(errs, fmt.Errorf("des3Key 应为 24 字节，实际 %d", len(p.DES3Key)))
+	}
+	if _, err := p.rsaKey(); err != nil {
+		errs = append(errs, fmt.Errorf("publicKey: %w", err))
+	}
+	if len(errs) > 0 {
+		sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+		return fmt.Errorf("协议 profile %q 无效: %w", p.Name, errors.Join(errs...))
+	}
+	return nil
+}
+
+func (p Protocol) rsaKey() (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(p.PublicKey))
+	if block == nil {
+		return nil, errors.New("failed to parse PEM block")
+	}
+	pubInterface, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	pub, ok := pubInterface.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("not a valid RSA public key")
+	}
+	return pub, nil
+}
+
+var (
+	protocolMu sync.RWMutex
+	protocol   = mustDefaultProtocol()
+)
+
+func mustDefaultProtocol() Protocol {
+	set, err := DefaultProtocols()
+	if err == nil {
+		var p Protocol
+		if p, err = set.Resolve(""); err == nil {
+			return p
+		}
+	}
+	panic(err)
+}
+
+// SetProtocol 替换进程内使用的协议常量
+func SetProtocol(p Protocol) {
+	protocolMu.Lock()
+	defer protocolMu.Unlock()
+	protocol = p
+}
+
+// CurrentProtocol 当前使用的协议常量
+func CurrentProtocol() Protocol {
+	protocolMu.RLock()
+	defer protocolMu.RUnlock()
+	return protocol
+}
@@ -0,0 +1,67 @@
+package sign
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDefaultProtocol(t *testing.T) {
+	p := CurrentProtocol()
+	if p.Name == "" || p.ClientType == "" {
+		t.Fatalf("内置默认 profile 未加载: %+v", p)
+	}
+	encrypted, err := Encrypt("U123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain, err := Decrypt(encrypted); err != nil || plain != "U123" {
+		t.Errorf("Decrypt = %q, %v", plain, err)
+	}
+	if _, err := B64("loginAuth"); err != nil {
+		t.Errorf("B64: %v", err)
+	}
+}
+
+func TestLoadProtocolsOverride(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "protocols.yaml")
+	err := os.WriteFile(path, []byte(`
+default: ios-9.7.0
+profiles:
+  ios-9.7.0:
+    extends: ios-9.6.1
+    clientType: "#9.7.0#channel50#iPhone 15 Pro#"
+  broken:
+    extends: ios-9.6.1
+    des3Key: "short"
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	set, err := LoadProtocols(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, err := set.Resolve("ios-9.6.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := set.Resolve("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name != "ios-9.7.0" || p.ClientType != "#9.7.0#channel50#iPhone 15 Pro#" {
+		t.Errorf("覆盖未生效: %+v", p)
+	}
+	if p.DES3Key != base.DES3Key || p.PublicKey != base.PublicKey || p.TicketURL != base.TicketURL {
+		t.Error("未写出的字段应继承自 ios-9.6.1")
+	}
+
+	if _, err := set.Resolve("broken"); err == nil || !strings.Contains(err.Error(), "des3Key") {
+		t.Errorf("无效的密钥应报错: %v", err)
+	}
+	if _, err := set.Resolve("android-1.0"); err == nil {
+		t.Error("不存在的 profile 应报错")
+	}
+}
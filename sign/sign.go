@@ -1,34 +1,24 @@
 package sign
 
 import (
+	"HighFrequencyTrading/endpoint"
 	"bytes"
 	"crypto/cipher"
 	"crypto/des"
 	"crypto/rand"
 	"crypto/rsa"
-	"crypto/x509"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
-	"encoding/pem"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	mathrand "math/rand"
 	"net/http"
-	"regexp"
 	"strings"
 	"time"
 )
 
-// 常量定义
-const (
-	des3Key      = "1234567`90koiuyhgtfrdews"
-	publicKeyPEM = `-----BEGIN PUBLIC KEY-----
-MIGfMA0GCSqGSIb3DQEBAQUAA4GNADCBiQKBgQDBkLT15ThVgz6/NOl6s8GNPofdWzWbCkWnkaAm7O2LjkM1H7dMvzkiqdxU02jamGRHLX/ZNMCXHnPcW/sDhiFCBN18qFvy8g6VYb9QtroI09e176s+ZCtiv7hbin2cCTj99iUpnEloZm19lwHyo69u5UMiPMpq0/XKBO8lYhN/gwIDAQAB
------END PUBLIC KEY-----`
-)
-
 // pkcs7Pad 对 data 做 PKCS7 填充
 func pkcs7Pad(data []byte, blockSize int) []byte {
 	padding := blockSize - len(data)%blockSize
@@ -55,7 +45,7 @@ func pkcs7Unpad(data []byte, blockSize int) ([]byte, error) {
 
 // Encrypt 使用 3DES CBC 模式对明文进行加密，并返回 hex 编码后的字符串
 func Encrypt(text string) (string, error) {
-	key := []byte(des3Key)
+	key := []byte(CurrentProtocol().DES3Key)
 	block, err := des.NewTripleDESCipher(key)
 	if err != nil {
 		return "", err
@@ -75,7 +65,7 @@ func Decrypt(hexText string) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	key := []byte(des3Key)
+	key := []byte(CurrentProtocol().DES3Key)
 	block, err := des.NewTripleDESCipher(key)
 	if err != nil {
 		return "", err
@@ -97,18 +87,10 @@ func Decrypt(hexText string) (string, error) {
 
 // B64 使用 RSA 公钥对 text 进行加密，并返回 base64 编码后的字符串
 func B64(text string) (string, error) {
-	block, _ := pem.Decode([]byte(publicKeyPEM))
-	if block == nil {
-		return "", errors.New("failed to parse PEM block")
-	}
-	pubInterface, err := x509.ParsePKIXPublicKey(block.Bytes)
+	pub, err := CurrentProtocol().rsaKey()
 	if err != nil {
 		return "", err
 	}
-	pub, ok := pubInterface.(*rsa.PublicKey)
-	if !ok {
-		return "", errors.New("not a valid RSA public key")
-	}
 	cipherText, err := rsa.EncryptPKCS1v15(rand.Reader, pub, []byte(text))
 	if err != nil {
 		return "", err
@@ -142,6 +124,17 @@ func randomSample(alphabet string, n int) string {
 
 // UserLoginNormal 模拟使用密码登录，返回 ticket 字符串（若失败返回空字符串和错误）
 func UserLoginNormal(phone, password string) (string, error) {
+	// loginAuth 需要截取密码前 6 位，过短的密码直接报错而不是 panic
+	if len(password) < 6 {
+		return "", &LoginError{Kind: ErrInvalidCredential, Err: errors.New("密码长度不足 6 位")}
+	}
+	return userLogin(phone, "4", password, password[:6])
+}
+
+// userLogin 调用 userLoginNormal 接口换取 ticket。
+// loginType 为 "4" 时 authentication 是服务密码，为 "5" 时是短信验证码；
+// authTail 为拼入 loginAuth 密文末尾的凭证
+func userLogin(phone, loginType, authentication, authTail string) (string, error) {
 	alphabet := "abcdef0123456789"
 	uuid0 := randomSample(alphabet, 8)
 	uuid1 := randomSample(alphabet, 4)
@@ -150,37 +143,27 @@ func UserLoginNormal(phone, password string) (string, error) {
 	// uuid3 := randomSample(alphabet, 4)
 	// uuid4 := randomSample(alphabet, 12)
 
+	proto := CurrentProtocol()
 	timestampStr := time.Now().Format("20060102150405")
-	loginAuth := fmt.Sprintf("iPhone 14 15.4.%s%s%s%s%s0$$$0.", uuid0, uuid1, phone, timestampStr, password[:6])
+	loginAuth := fmt.Sprintf("%s%s%s%s%s%s0$$$0.", proto.LoginAuthPrefix, uuid0, uuid1, phone, timestampStr, authTail)
 	encryptedLoginAuth, err := B64(loginAuth)
 	if err != nil {
 		return "", err
 	}
 
 	payload := map[string]interface{}{
-		"headerInfos": map[string]interface{}{
-			"code":           "userLoginNormal",
-			"timestamp":      timestampStr,
-			"broadAccount":   "",
-			"broadToken":     "",
-			"clientType":     "#9.6.1#channel50#iPhone 14 Pro Max#",
-			"shopId":         "20002",
-			"source":         "110003",
-			"sourcePassword": "Sid98s",
-			"token":          "",
-			"userLoginName":  phone,
-		},
+		"headerInfos": headerInfos("userLoginNormal", phone, timestampStr),
 		"content": map[string]interface{}{
 			"attach": "test",
 			"fieldData": map[string]interface{}{
-				"loginType":                  "4",
+				"loginType":                  loginType,
 				"accountType":                "",
 				"loginAuthCipherAsymmertric": encryptedLoginAuth,
 				"deviceUid":                  uuid0 + uuid1 + uuid2,
 				"phoneNum":                   EncodePhone(phone),
 				"isChinatelecom":             "0",
-				"systemVersion":              "15.4.0",
-				"authentication":             password,
+				"systemVersion":              proto.SystemVersion,
+				"authentication":             authentication,
 			},
 		},
 	}
@@ -191,50 +174,58 @@ func UserLoginNormal(phone, password string) (string, error) {
 	}
 
 	client := &http.Client{}
-	req, err := http.NewRequest("POST", "https://appgologin.189.cn:9031/login/client/userLoginNormal", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequest("POST", endpoint.Login("/login/client/userLoginNormal"), bytes.NewBuffer(jsonData))
 	if err != nil {
 		return "", err
 	}
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Linux; Android 13; Build/Example) Chrome/104.0 Mobile Safari/537.36")
-	req.Header.Set("Referer", "https://wapact.189.cn:9001/JinDouMall/JinDouMall_independentDetails.html")
+	req.Header.Set("User-Agent", proto.WebUserAgent)
+	req.Header.Set("Referer", MallReferer())
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("登录请求失败: %v", err)
+		return "", &LoginError{Kind: ErrServiceUnavailable, Err: err}
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", statusError(resp.StatusCode)
+	}
 
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return "", err
+		return "", &LoginError{Kind: ErrServiceUnavailable, Status: resp.StatusCode, Err: err}
 	}
 
 	var respJSON map[string]interface{}
 	if err := json.Unmarshal(body, &respJSON); err != nil {
-		return "", err
+		return "", protocolError("响应不是 JSON: %v", err)
 	}
 
 	// 遍历 JSON 结构：responseData -> data -> loginSuccessResult
 	responseData, ok := respJSON["responseData"].(map[string]interface{})
 	if !ok {
-		return "", errors.New("登录失败，未获取到 responseData")
+		return "", protocolError("未获取到 responseData")
+	}
+	code, _ := responseData["resultCode"].(string)
+	desc, _ := responseData["resultDesc"].(string)
+	if code != "" && code != "0000" {
+		return "", resultError(code, desc)
 	}
 	data, ok := responseData["data"].(map[string]interface{})
 	if !ok {
-		return "", errors.New("登录失败，未获取到 data")
+		return "", protocolError("未获取到 data")
 	}
 	loginSuccessResult, ok := data["loginSuccessResult"].(map[string]interface{})
 	if !ok {
-		return "", errors.New("登录失败，未获取到 loginSuccessResult")
+		return "", protocolError("未获取到 loginSuccessResult")
 	}
 	userId, ok := loginSuccessResult["userId"].(string)
 	if !ok {
-		return "", errors.New("登录失败，userId 缺失")
+		return "", protocolError("userId 缺失")
 	}
 	token, ok := loginSuccessResult["token"].(string)
 	if !ok {
-		return "", errors.New("登录失败，token 缺失")
+		return "", protocolError("token 缺失")
 	}
 
 	ticket, err := GetTicket(phone, userId, token)
@@ -244,58 +235,57 @@ func UserLoginNormal(phone, password string) (string, error) {
 	return ticket, nil
 }
 
-// GetTicket 根据登录返回的 userId 与 token，通过调用 getSingle 接口获取并解密 ticket
-func GetTicket(phone, userId, token string) (string, error) {
-	timestampStr := time.Now().Format("20060102150405")
-	encryptedUserId, err := Encrypt(userId)
-	if err != nil {
-		return "", err
+// MallReferer 金豆商城页面地址，兑换等接口校验 Referer
+func MallReferer() string {
+	return endpoint.Mall("/JinDouMall/JinDouMall_independentDetails.html")
+}
+
+// headerInfos 客户端接口公共请求头
+func headerInfos(code, phone, timestampStr string) map[string]interface{} {
+	proto := CurrentProtocol()
+	return map[string]interface{}{
+		"code":           code,
+		"timestamp":      timestampStr,
+		"broadAccount":   "",
+		"broadToken":     "",
+		"clientType":     proto.ClientType,
+		"shopId":         proto.ShopID,
+		"source":         proto.Source,
+		"sourcePassword": proto.SourcePassword,
+		"token":          "",
+		"userLoginName":  phone,
 	}
+}
 
-	xmlPayload := fmt.Sprintf(
-		`<Request><HeaderInfos>`+
-			`<Code>getSingle</Code><Timestamp>%s</Timestamp>`+
-			`<BroadAccount></BroadAccount><BroadToken></BroadToken>`+
-			`<ClientType>#9.6.1#channel50#iPhone 14 Pro Max#</ClientType>`+
-			`<ShopId>20002</ShopId><Source>110003</Source>`+
-			`<SourcePassword>Sid98s</SourcePassword>`+
-			`<Token>%s</Token>`+
-			`<UserLoginName>%s</UserLoginName>`+
-			`</HeaderInfos><Content><Attach>test</Attach>`+
-			`<FieldData><TargetId>%s</TargetId>`+
-			`<Url>4a6862274835b451</Url></FieldData></Content></Request>`,
-		timestampStr, token, phone, encryptedUserId,
-	)
+// getSingleFields getSingle 请求的 FieldData
+type getSingleFields struct {
+	TargetId string
+	Url      string
+}
 
-	client := &http.Client{}
-	req, err := http.NewRequest("POST", "https://appgologin.189.cn:9031/map/clientXML", strings.NewReader(xmlPayload))
+// GetTicket 根据登录返回的 userId 与 token，通过调用 getSingle 接口获取并解密 ticket
+func GetTicket(phone, userId, token string) (string, error) {
+	encryptedUserId, err := Encrypt(userId)
 	if err != nil {
 		return "", err
 	}
-	req.Header.Set("Content-Type", "application/xml")
-	req.Header.Set("User-Agent", "CtClient;10.4.1;Android;13;ExampleClient")
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("获取 ticket 请求失败: %v", err)
+	req := NewXMLRequest("getSingle", phone, token, getSingleFields{
+		TargetId: encryptedUserId,
+		Url:      CurrentProtocol().TicketURL,
+	})
+	var data struct {
+		Ticket string
 	}
-	defer resp.Body.Close()
-
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
+	if err := CallClientXML(req, &data); err != nil {
 		return "", err
 	}
-
-	// 正则匹配 Ticket
-	re := regexp.MustCompile(`<Ticket>(.*?)</Ticket>`)
-	matches := re.FindStringSubmatch(string(body))
-	if len(matches) < 2 {
-		return "", errors.New("未能在响应中找到 Ticket")
+	if data.Ticket == "" {
+		return "", protocolError("未能在响应中找到 Ticket")
 	}
 
-	decryptedTicket, err := Decrypt(matches[1])
+	decryptedTicket, err := Decrypt(data.Ticket)
 	if err != nil {
-		return "", fmt.Errorf("Ticket 解密失败: %v", err)
+		return "", protocolError("Ticket 解密失败: %v", err)
 	}
 	return decryptedTicket, nil
 }
@@ -0,0 +1,78 @@
+package sign
+
+import (
+	"HighFrequencyTrading/endpoint"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+var smsCodePattern = regexp.MustCompile(`^\d{4,8}$`)
+
+// RequestSMSCode 请求向 phone 发送登录验证码
+func RequestSMSCode(phone string) error {
+	timestampStr := time.Now().Format("20060102150405")
+	payload := map[string]interface{}{
+		"headerInfos": headerInfos("getRandomCode", phone, timestampStr),
+		"content": map[string]interface{}{
+			"attach": "test",
+			"fieldData": map[string]interface{}{
+				"phoneNum":  EncodePhone(phone),
+				"scene":     "8",
+				"loginType": "5",
+			},
+		},
+	}
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	req, err := http.NewRequest("POST", endpoint.Login("/login/client/getRandomCode"), bytes.NewBuffer(jsonData))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", CurrentProtocol().WebUserAgent)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return &LoginError{Kind: ErrServiceUnavailable, Err: fmt.Errorf("验证码请求失败: %w", err)}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return statusError(resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return &LoginError{Kind: ErrServiceUnavailable, Status: resp.StatusCode, Err: err}
+	}
+
+	var respJSON struct {
+		ResponseData struct {
+			ResultCode string `json:"resultCode"`
+			ResultDesc string `json:"resultDesc"`
+		} `json:"responseData"`
+	}
+	if err := json.Unmarshal(body, &respJSON); err != nil {
+		return protocolError("验证码响应无法解析: %v", err)
+	}
+	if respJSON.ResponseData.ResultCode != "0000" {
+		return resultError(respJSON.ResponseData.ResultCode, respJSON.ResponseData.ResultDesc)
+	}
+	return nil
+}
+
+// UserLoginSMS 使用短信验证码登录，返回 ticket；验证码格式不正确时返回 ErrInvalidInput，不发出请求
+func UserLoginSMS(phone, code string) (string, error) {
+	if !smsCodePattern.MatchString(code) {
+		return "", &LoginError{Kind: ErrInvalidInput, Err: errors.New("验证码应为 4 到 8 位数字")}
+	}
+	return userLogin(phone, "5", code, code)
+}
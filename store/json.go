@@ -0,0 +1,173 @@
+package store
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"HighFrequencyTrading/ledger"
+	"HighFrequencyTrading/util"
+)
+
+// JSONStore : 基于数据目录下 JSON 文件的存储。
+// 账本与运行历史只追加；缓存与元数据在文件锁内读-改-写，原子替换
+type JSONStore struct {
+	dir    string
+	ledger *ledger.Ledger
+	mu     sync.Mutex
+}
+
+// OpenJSON : 打开 dir 下的 JSON 存储，文件在首次写入时创建
+func OpenJSON(dir string) *JSONStore {
+	return &JSONStore{dir: dir, ledger: ledger.Open(filepath.Join(dir, LedgerFile))}
+}
+
+func (s *JSONStore) file(name string) string { return filepath.Join(s.dir, name) }
+
+func (s *JSONStore) Append(r ledger.Record) error { return s.ledger.Append(r) }
+
+func (s *JSONStore) Records() ([]ledger.Record, error) { return s.ledger.Records() }
+
+func (s *JSONStore) PruneRecords(before time.Time) (int, error) {
+	return s.ledger.Rewrite(func(r ledger.Record) bool { return !r.Time.Before(before) })
+}
+
+func (s *JSONStore) Cache() (map[string]Ticket, error) {
+	c := make(map[string]Ticket)
+	err := s.locked(CacheFile, func() error { return readJSON(s.file(CacheFile), &c) })
+	return c, err
+}
+
+func (s *JSONStore) PutCache(phone string, t Ticket) error {
+	return updateMap(s, CacheFile, 0600, func(m map[string]Ticket) { m[phone] = t })
+}
+
+func (s *JSONStore) DeleteCache(phone string) error {
+	return updateMap(s, CacheFile, 0600, func(m map[string]Ticket) { delete(m, phone) })
+}
+
+func (s *JSONStore) LoginStates() (map[string]LoginState, error) {
+	m := make(map[string]LoginState)
+	err := s.locked(LoginFile, func() error { return readJSON(s.file(LoginFile), &m) })
+	return m, err
+}
+
+func (s *JSONStore) PutLoginState(phone string, st LoginState) error {
+	return updateMap(s, LoginFile, 0600, func(m map[string]LoginState) { m[phone] = st })
+}
+
+func (s *JSONStore) DeleteLoginState(phone string) error {
+	return updateMap(s, LoginFile, 0600, func(m map[string]LoginState) { delete(m, phone) })
+}
+
+// SaveRun : 追加一行，读取时同一 ID 以最后一行为准
+func (s *JSONStore) SaveRun(r Run) error {
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return s.locked(RunsFile, func() error {
+		f, err := os.OpenFile(s.file(RunsFile), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(append(line, '\n')); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	})
+}
+
+func (s *JSONStore) Runs() ([]Run, error) {
+	byID := make(map[string]Run)
+	err := s.locked(RunsFile, func() error {
+		f, err := os.Open(s.file(RunsFile))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		defer f.Close()
+		sc := bufio.NewScanner(f)
+		for sc.Scan() {
+			var r Run
+			// 跳过写了一半的行
+			if json.Unmarshal(sc.Bytes(), &r) == nil && r.ID != "" {
+				byID[r.ID] = r
+			}
+		}
+		return sc.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	runs := make([]Run, 0, len(byID))
+	for _, r := range byID {
+		runs = append(runs, r)
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].StartedAt.Before(runs[j].StartedAt) })
+	return runs, nil
+}
+
+func (s *JSONStore) Meta(key string) (string, error) {
+	m := make(map[string]string)
+	err := s.locked(MetaFile, func() error { return readJSON(s.file(MetaFile), &m) })
+	return m[key], err
+}
+
+func (s *JSONStore) SetMeta(key, value string) error {
+	return updateMap(s, MetaFile, 0644, func(m map[string]string) { m[key] = value })
+}
+
+func (s *JSONStore) Close() error { return nil }
+
+// locked : 在进程内互斥与文件锁的保护下执行 fn
+func (s *JSONStore) locked(name string, fn func() error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lock, err := util.Lock(s.file(name))
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+	return fn()
+}
+
+// updateMap : 读出 map 文件，修改后原子写回
+func updateMap[V any](s *JSONStore, name string, perm os.FileMode, fn func(map[string]V)) error {
+	return s.locked(name, func() error {
+		m := make(map[string]V)
+		if err := readJSON(s.file(name), &m); err != nil {
+			return err
+		}
+		fn(m)
+		bt, err := json.Marshal(m)
+		if err != nil {
+			return err
+		}
+		return util.WriteFileAtomic(s.file(name), bt, perm)
+	})
+}
+
+// readJSON : 读取 JSON 文件到 v，文件不存在时不报错
+func readJSON(path string, v interface{}) error {
+	dat, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	if err := json.Unmarshal(dat, v); err != nil {
+		return fmt.Errorf("解析 %s 失败: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package store
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"HighFrequencyTrading/ledger"
+)
+
+// LegacyExchangeLogFile : 旧版兑换日志（年月 -> 话费标题 -> []手机号）
+const LegacyExchangeLogFile = "电信金豆换话费.log"
+
+// MetaLegacyImported : 记录旧数据已导入的元数据键，值为导入时间
+const MetaLegacyImported = "legacy_imported"
+
+// ImportResult : 一次导入的统计
+type ImportResult struct {
+	Records int  // 新增的兑换记录
+	Tickets int  // 新增的缓存 ticket
+	Skipped bool // 之前已导入过，本次未执行
+}
+
+// ImportLegacy : 将 dir 下的旧数据一次性导入 st：
+//   - ledger.jsonl 中的记录（从 JSON 后端切换到其他后端时）
+//   - 电信金豆换话费.log 中的每个 年月/标题/手机号 转成一条成功记录，时间记为当月 1 日
+//   - chinaTelecom_cache.json 中的 ticket
+//
+// 旧版兑换日志与缓存依次在 dir 与 legacyDirs（如旧版本运行时的工作目录）中查找。
+// 已存在的记录与 ticket 不会重复写入，因此对 JSON 后端自身的文件导入是空操作。
+// 导入完成后写入 MetaLegacyImported，之后除非 force 否则直接跳过
+func ImportLegacy(st Store, dir string, force bool, legacyDirs ...string) (ImportResult, error) {
+	var res ImportResult
+	if !force {
+		done, err := st.Meta(MetaLegacyImported)
+		if err != nil {
+			return res, err
+		}
+		if done != "" {
+			res.Skipped = true
+			return res, nil
+		}
+	}
+
+	existing, err := st.Records()
+	if err != nil {
+		return res, err
+	}
+	seen := make(map[string]bool, len(existing))
+	successes := make(map[string]bool)
+	for _, r := range existing {
+		seen[recordKey(r)] = true
+		if r.Outcome == ledger.OutcomeSuccess {
+			successes[r.Month()+"|"+r.Phone+"|"+r.Item] = true
+		}
+	}
+
+	// 1. JSON 后端的账本（先于旧日志导入，以便旧日志按月去重）
+	records, err := ledger.Open(filepath.Join(dir, LedgerFile)).Records()
+	if err != nil {
+		return res, err
+	}
+	for _, r := range records {
+		if seen[recordKey(r)] {
+			continue
+		}
+		if err := st.Append(r); err != nil {
+			return res, err
+		}
+		seen[recordKey(r)] = true
+		if r.Outcome == ledger.OutcomeSuccess {
+			successes[r.Month()+"|"+r.Phone+"|"+r.Item] = true
+		}
+		res.Records++
+	}
+
+	cache, err := st.Cache()
+	if err != nil {
+		return res, err
+	}
+	for _, d := range append([]string{dir}, legacyDirs...) {
+		// 2. 旧版兑换日志：只有成功记录，同月同手机号同商品已存在时跳过
+		var dhjl map[string]map[string][]string
+		if err := readJSON(filepath.Join(d, LegacyExchangeLogFile), &dhjl); err != nil {
+			return res, err
+		}
+		for month, items := range dhjl {
+			tm, err := time.ParseInLocation("200601", month, time.Local)
+			if err != nil {
+				return res, fmt.Errorf("%s 中的年月 %q 无法解析: %w", LegacyExchangeLogFile, month, err)
+			}
+			for item, phones := range items {
+				for _, phone := range phones {
+					key := month + "|" + phone + "|" + item
+					if phone == "" || successes[key] {
+						continue
+					}
+					r := ledger.Record{
+						Time:    tm,
+						Phone:   phone,
+						Item:    item,
+						Outcome: ledger.OutcomeSuccess,
+						Message: "从旧版兑换日志导入",
+					}
+					if err := st.Append(r); err != nil {
+						return res, err
+					}
+					successes[key] = true
+					res.Records++
+				}
+			}
+		}
+
+		// 3. ticket 缓存（原样导入，是否加密由配置决定），先找到的优先
+		var legacy map[string]Ticket
+		if err := readJSON(filepath.Join(d, CacheFile), &legacy); err != nil {
+			return res, err
+		}
+		for phone, ticket := range legacy {
+			if _, ok := cache[phone]; ok {
+				continue
+			}
+			if err := st.PutCache(phone, ticket); err != nil {
+				return res, err
+			}
+			cache[phone] = ticket
+			res.Tickets++
+		}
+	}
+
+	return res, st.SetMeta(MetaLegacyImported, time.Now().Format(time.RFC3339))
+}
+
+func recordKey(r ledger.Record) string {
+	return fmt.Sprintf("%d|%s|%s|%s|%s", r.Time.UnixNano(), r.Session, r.Phone, r.Item, r.Outcome)
+}
@@ -0,0 +1,151 @@
+package store
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"HighFrequencyTrading/ledger"
+)
+
+// ArchiveDir : 数据目录下存放按月归档账本的子目录
+const ArchiveDir = "archive"
+
+const archivePrefix, archiveSuffix = "ledger-", ".jsonl"
+
+// RetentionResult : 一次保留策略执行的统计
+type RetentionResult struct {
+	Archived       int      // 移入归档的记录数
+	Pruned         int      // 彻底删除的记录数（含归档文件中的）
+	ArchivedMonths []string // 本次写入的归档月份
+	PrunedMonths   []string // 本次删除的归档月份
+}
+
+// ApplyRetention : 执行保留策略（月份均包含当月）：
+//   - keepMonths > 0 时，早于最近 keepMonths 个月的记录移入 archiveDir/ledger-YYYYMM.jsonl
+//   - pruneMonths > 0 时，早于最近 pruneMonths 个月的记录与归档文件直接删除
+//
+// 先写归档再从存储中删除，中途失败时重新执行不会丢失或重复记录
+func ApplyRetention(st Store, archiveDir string, keepMonths, pruneMonths int, now time.Time) (RetentionResult, error) {
+	var res RetentionResult
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+
+	if pruneMonths > 0 {
+		before := monthStart.AddDate(0, -(pruneMonths - 1), 0)
+		n, err := st.PruneRecords(before)
+		if err != nil {
+			return res, err
+		}
+		res.Pruned += n
+
+		months, err := ArchivedMonths(archiveDir)
+		if err != nil {
+			return res, err
+		}
+		for _, month := range months {
+			if month >= before.Format("200601") {
+				continue
+			}
+			records, err := ArchivedRecords(archiveDir, month)
+			if err != nil {
+				return res, err
+			}
+			if err := os.Remove(archivePath(archiveDir, month)); err != nil {
+				return res, err
+			}
+			_ = os.Remove(archivePath(archiveDir, month) + ".lock")
+			res.Pruned += len(records)
+			res.PrunedMonths = append(res.PrunedMonths, month)
+		}
+	}
+
+	if keepMonths > 0 {
+		before := monthStart.AddDate(0, -(keepMonths - 1), 0)
+		records, err := st.Records()
+		if err != nil {
+			return res, err
+		}
+		byMonth := make(map[string][]ledger.Record)
+		for _, r := range records {
+			if r.Time.Before(before) {
+				byMonth[r.Month()] = append(byMonth[r.Month()], r)
+			}
+		}
+		if len(byMonth) == 0 {
+			return res, nil
+		}
+		if err := os.MkdirAll(archiveDir, 0700); err != nil {
+			return res, err
+		}
+		for month, records := range byMonth {
+			if err := appendArchive(archiveDir, month, records); err != nil {
+				return res, err
+			}
+			res.ArchivedMonths = append(res.ArchivedMonths, month)
+		}
+		sort.Strings(res.ArchivedMonths)
+		if res.Archived, err = st.PruneRecords(before); err != nil {
+			return res, err
+		}
+	}
+	return res, nil
+}
+
+// appendArchive : 追加到当月归档，已归档的记录（上次中途失败残留的）跳过
+func appendArchive(dir, month string, records []ledger.Record) error {
+	l := ledger.Open(archivePath(dir, month))
+	existing, err := l.Records()
+	if err != nil {
+		return err
+	}
+	seen := make(map[string]bool, len(existing))
+	for _, r := range existing {
+		seen[recordKey(r)] = true
+	}
+	for _, r := range records {
+		if seen[recordKey(r)] {
+			continue
+		}
+		if err := l.Append(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func archivePath(dir, month string) string {
+	return filepath.Join(dir, archivePrefix+month+archiveSuffix)
+}
+
+// ArchivedMonths : 已归档的月份，升序
+func ArchivedMonths(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var months []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, archivePrefix) || !strings.HasSuffix(name, archiveSuffix) {
+			continue
+		}
+		months = append(months, strings.TrimSuffix(strings.TrimPrefix(name, archivePrefix), archiveSuffix))
+	}
+	sort.Strings(months)
+	return months, nil
+}
+
+// ArchivedRecords : 读取某月的归档记录，月份未归档时返回空
+func ArchivedRecords(dir, month string) ([]ledger.Record, error) {
+	path := archivePath(dir, month)
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return ledger.Open(path).Records()
+}
@@ -0,0 +1,325 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"net/url"
+	"path/filepath"
+	"time"
+
+	"HighFrequencyTrading/ledger"
+
+	_ "modernc.org/sqlite" // 纯 Go 实现，无需 cgo
+)
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS records (
+	id          INTEGER PRIMARY KEY AUTOINCREMENT,
+	time        TEXT NOT NULL,
+	session     TEXT NOT NULL DEFAULT '',
+	phone       TEXT NOT NULL,
+	item        TEXT NOT NULL,
+	activity_id TEXT NOT NULL DEFAULT '',
+	outcome     TEXT NOT NULL,
+	http_status INTEGER NOT NULL DEFAULT 0,
+	code        TEXT NOT NULL DEFAULT '',
+	message     TEXT NOT NULL DEFAULT '',
+	latency_ms  INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS records_phone ON records (phone, time);
+CREATE TABLE IF NOT EXISTS cache (
+	phone        TEXT PRIMARY KEY,
+	ticket       TEXT NOT NULL,
+	updated_at   TEXT NOT NULL,
+	issued_at    TEXT NOT NULL DEFAULT '',
+	validated_at TEXT NOT NULL DEFAULT ''
+);
+CREATE TABLE IF NOT EXISTS login_state (
+	phone               TEXT PRIMARY KEY,
+	failures            INTEGER NOT NULL DEFAULT 0,
+	credential_failures INTEGER NOT NULL DEFAULT 0,
+	last_error          TEXT NOT NULL DEFAULT '',
+	last_failure        TEXT NOT NULL DEFAULT '',
+	next_attempt        TEXT NOT NULL DEFAULT '',
+	suspended_at        TEXT NOT NULL DEFAULT '',
+	fingerprint         TEXT NOT NULL DEFAULT ''
+);
+CREATE TABLE IF NOT EXISTS runs (
+	id          TEXT PRIMARY KEY,
+	profile     TEXT NOT NULL DEFAULT '',
+	session     TEXT NOT NULL DEFAULT '',
+	started_at  TEXT NOT NULL,
+	finished_at TEXT NOT NULL DEFAULT '',
+	accounts    INTEGER NOT NULL DEFAULT 0,
+	attempts    INTEGER NOT NULL DEFAULT 0,
+	successes   INTEGER NOT NULL DEFAULT 0,
+	error       TEXT NOT NULL DEFAULT ''
+);
+CREATE TABLE IF NOT EXISTS meta (
+	key   TEXT PRIMARY KEY,
+	value TEXT NOT NULL
+);
+`
+
+// SQLiteStore : 数据目录下 telecom.db 中的存储，并发进程由 SQLite 自身的锁保证一致
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// OpenSQLite : 打开（必要时创建）dir 下的数据库并建表
+func OpenSQLite(dir string) (*SQLiteStore, error) {
+	// WAL 允许读写并发；busy_timeout 让并发运行的进程排队等待而不是立即报错
+	dsn := "file:" + filepath.Join(dir, SQLiteFile) + "?" + url.Values{
+		"_pragma": {"busy_timeout(5000)", "journal_mode(WAL)", "synchronous(FULL)"},
+	}.Encode()
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	// 早期版本的 cache 表没有时间列
+	for _, col := range []string{"issued_at", "validated_at"} {
+		if err := ensureColumn(db, "cache", col, "TEXT NOT NULL DEFAULT ''"); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+// ensureColumn : 列不存在时追加
+func ensureColumn(db *sql.DB, table, column, decl string) error {
+	rows, err := db.Query(`SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	_, err = db.Exec(`ALTER TABLE ` + table + ` ADD COLUMN ` + column + ` ` + decl)
+	return err
+}
+
+func (s *SQLiteStore) Append(r ledger.Record) error {
+	_, err := s.db.Exec(`INSERT INTO records
+		(time, session, phone, item, activity_id, outcome, http_status, code, message, latency_ms)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		formatTime(r.Time), r.Session, r.Phone, r.Item, r.ActivityID, r.Outcome,
+		r.HTTPStatus, r.Code, r.Message, r.LatencyMs)
+	return err
+}
+
+func (s *SQLiteStore) Records() ([]ledger.Record, error) {
+	rows, err := s.db.Query(`SELECT time, session, phone, item, activity_id, outcome,
+		http_status, code, message, latency_ms FROM records ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []ledger.Record
+	for rows.Next() {
+		var r ledger.Record
+		var tm string
+		if err := rows.Scan(&tm, &r.Session, &r.Phone, &r.Item, &r.ActivityID, &r.Outcome,
+			&r.HTTPStatus, &r.Code, &r.Message, &r.LatencyMs); err != nil {
+			return nil, err
+		}
+		r.Time = parseTime(tm)
+		res = append(res, r)
+	}
+	return res, rows.Err()
+}
+
+// PruneRecords : 时间以带时区的文本保存，不同时区下字符串比较不可靠，故在 Go 中逐条判断
+func (s *SQLiteStore) PruneRecords(before time.Time) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`SELECT id, time FROM records`)
+	if err != nil {
+		return 0, err
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		var tm string
+		if err := rows.Scan(&id, &tm); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if parseTime(tm).Before(before) {
+			ids = append(ids, id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	for _, id := range ids {
+		if _, err := tx.Exec(`DELETE FROM records WHERE id = ?`, id); err != nil {
+			return 0, err
+		}
+	}
+	return len(ids), tx.Commit()
+}
+
+func (s *SQLiteStore) Cache() (map[string]Ticket, error) {
+	rows, err := s.db.Query(`SELECT phone, ticket, issued_at, validated_at FROM cache`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	c := make(map[string]Ticket)
+	for rows.Next() {
+		var phone, issued, validated string
+		var t Ticket
+		if err := rows.Scan(&phone, &t.Ticket, &issued, &validated); err != nil {
+			return nil, err
+		}
+		t.IssuedAt, t.ValidatedAt = parseTime(issued), parseTime(validated)
+		c[phone] = t
+	}
+	return c, rows.Err()
+}
+
+func (s *SQLiteStore) PutCache(phone string, t Ticket) error {
+	_, err := s.db.Exec(`INSERT INTO cache (phone, ticket, updated_at, issued_at, validated_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (phone) DO UPDATE SET ticket = excluded.ticket, updated_at = excluded.updated_at,
+			issued_at = excluded.issued_at, validated_at = excluded.validated_at`,
+		phone, t.Ticket, formatTime(time.Now()), formatZero(t.IssuedAt), formatZero(t.ValidatedAt))
+	return err
+}
+
+func (s *SQLiteStore) DeleteCache(phone string) error {
+	_, err := s.db.Exec(`DELETE FROM cache WHERE phone = ?`, phone)
+	return err
+}
+
+func (s *SQLiteStore) LoginStates() (map[string]LoginState, error) {
+	rows, err := s.db.Query(`SELECT phone, failures, credential_failures, last_error,
+		last_failure, next_attempt, suspended_at, fingerprint FROM login_state`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	m := make(map[string]LoginState)
+	for rows.Next() {
+		var phone, last, next, suspended string
+		var st LoginState
+		if err := rows.Scan(&phone, &st.Failures, &st.CredentialFailures, &st.LastError,
+			&last, &next, &suspended, &st.Fingerprint); err != nil {
+			return nil, err
+		}
+		st.LastFailure, st.NextAttempt, st.SuspendedAt = parseTime(last), parseTime(next), parseTime(suspended)
+		m[phone] = st
+	}
+	return m, rows.Err()
+}
+
+func (s *SQLiteStore) PutLoginState(phone string, st LoginState) error {
+	_, err := s.db.Exec(`INSERT INTO login_state
+		(phone, failures, credential_failures, last_error, last_failure, next_attempt, suspended_at, fingerprint)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (phone) DO UPDATE SET failures = excluded.failures,
+			credential_failures = excluded.credential_failures, last_error = excluded.last_error,
+			last_failure = excluded.last_failure, next_attempt = excluded.next_attempt,
+			suspended_at = excluded.suspended_at, fingerprint = excluded.fingerprint`,
+		phone, st.Failures, st.CredentialFailures, st.LastError, formatZero(st.LastFailure),
+		formatZero(st.NextAttempt), formatZero(st.SuspendedAt), st.Fingerprint)
+	return err
+}
+
+func (s *SQLiteStore) DeleteLoginState(phone string) error {
+	_, err := s.db.Exec(`DELETE FROM login_state WHERE phone = ?`, phone)
+	return err
+}
+
+func (s *SQLiteStore) SaveRun(r Run) error {
+	_, err := s.db.Exec(`INSERT INTO runs
+		(id, profile, session, started_at, finished_at, accounts, attempts, successes, error)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET profile = excluded.profile, session = excluded.session,
+			started_at = excluded.started_at, finished_at = excluded.finished_at,
+			accounts = excluded.accounts, attempts = excluded.attempts,
+			successes = excluded.successes, error = excluded.error`,
+		r.ID, r.Profile, r.Session, formatTime(r.StartedAt), formatZero(r.FinishedAt),
+		r.Accounts, r.Attempts, r.Successes, r.Error)
+	return err
+}
+
+func (s *SQLiteStore) Runs() ([]Run, error) {
+	rows, err := s.db.Query(`SELECT id, profile, session, started_at, finished_at,
+		accounts, attempts, successes, error FROM runs ORDER BY started_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var res []Run
+	for rows.Next() {
+		var r Run
+		var started, finished string
+		if err := rows.Scan(&r.ID, &r.Profile, &r.Session, &started, &finished,
+			&r.Accounts, &r.Attempts, &r.Successes, &r.Error); err != nil {
+			return nil, err
+		}
+		r.StartedAt, r.FinishedAt = parseTime(started), parseTime(finished)
+		res = append(res, r)
+	}
+	return res, rows.Err()
+}
+
+func (s *SQLiteStore) Meta(key string) (string, error) {
+	var v string
+	err := s.db.QueryRow(`SELECT value FROM meta WHERE key = ?`, key).Scan(&v)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return v, err
+}
+
+func (s *SQLiteStore) SetMeta(key, value string) error {
+	_, err := s.db.Exec(`INSERT INTO meta (key, value) VALUES (?, ?)
+		ON CONFLICT (key) DO UPDATE SET value = excluded.value`, key, value)
+	return err
+}
+
+func (s *SQLiteStore) Close() error { return s.db.Close() }
+
+// 时间统一以 RFC3339Nano 文本保存，按字符串排序即按时间排序（同一时区下）
+func formatTime(t time.Time) string { return t.Format(time.RFC3339Nano) }
+
+// formatZero : 零值时间保存为空串
+func formatZero(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return formatTime(t)
+}
+
+func parseTime(s string) time.Time {
+	if s == "" {
+		return time.Time{}
+	}
+	t, _ := time.Parse(time.RFC3339Nano, s)
+	return t
+}
@@ -0,0 +1,130 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"HighFrequencyTrading/ledger"
+)
+
+// 存储后端
+const (
+	BackendJSON   = "json"   // 数据目录下的 JSON / JSONL 文件（默认）
+	BackendSQLite = "sqlite" // 内嵌的纯 Go SQLite 数据库
+)
+
+// 各后端使用的文件名
+const (
+	LedgerFile = "ledger.jsonl"            // 兑换账本
+	CacheFile  = "chinaTelecom_cache.json" // ticket 缓存，沿用旧文件名
+	RunsFile   = "runs.jsonl"              // 运行历史
+	MetaFile   = "store_meta.json"         // 迁移标记等元数据
+	LoginFile  = "login_state.json"        // 登录失败记录
+	SQLiteFile = "telecom.db"
+)
+
+// Ticket : 缓存的 ticket 及其签发、最近验证时间
+type Ticket struct {
+	Ticket      string    `json:"ticket"`
+	IssuedAt    time.Time `json:"issuedAt"`    // 为零值表示来自旧缓存，签发时间未知
+	ValidatedAt time.Time `json:"validatedAt"` // 最近一次确认有效的时间
+}
+
+// UnmarshalJSON 兼容旧缓存格式：值直接是 ticket 字符串
+func (t *Ticket) UnmarshalJSON(data []byte) error {
+	var s string
+	if json.Unmarshal(data, &s) == nil {
+		*t = Ticket{Ticket: s}
+		return nil
+	}
+	type plain Ticket
+	return json.Unmarshal(data, (*plain)(t))
+}
+
+// Expired : 按 ttl 判断是否过期，签发时间未知或 ttl<=0 时不判定过期
+func (t Ticket) Expired(now time.Time, ttl time.Duration) bool {
+	return ttl > 0 && !t.IssuedAt.IsZero() && now.Sub(t.IssuedAt) > ttl
+}
+
+// LoginState : 单个手机号的登录失败记录，登录成功后删除
+type LoginState struct {
+	Failures           int       `json:"failures"`              // 连续失败次数，不含服务暂不可用
+	CredentialFailures int       `json:"credentialFailures"`    // 其中连续的凭证错误次数
+	LastError          string    `json:"lastError"`             // 最近一次失败的原因
+	LastFailure        time.Time `json:"lastFailure"`           // 最近一次失败的时间
+	NextAttempt        time.Time `json:"nextAttempt"`           // 退避期内不再自动登录
+	SuspendedAt        time.Time `json:"suspendedAt,omitempty"` // 非零值表示已暂停自动登录
+	Fingerprint        string    `json:"fingerprint,omitempty"` // 暂停时凭证的摘要，凭证变化后自动解除
+}
+
+// Suspended : 是否已暂停自动登录
+func (s LoginState) Suspended() bool {
+	return !s.SuspendedAt.IsZero()
+}
+
+// Run : 一次运行的概要
+type Run struct {
+	ID         string    `json:"id"`
+	Profile    string    `json:"profile"`
+	Session    string    `json:"session"`
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt,omitempty"` // 为零值表示未正常结束
+	Accounts   int       `json:"accounts"`
+	Attempts   int       `json:"attempts"`
+	Successes  int       `json:"successes"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Store : 兑换账本、ticket 缓存与运行历史的持久化接口。
+// 缓存中的 ticket 由调用方决定是否加密，Store 只按原样保存
+type Store interface {
+	// Append 追加一条兑换记录
+	Append(r ledger.Record) error
+	// Records 按写入顺序返回全部兑换记录
+	Records() ([]ledger.Record, error)
+	// PruneRecords 删除时间早于 before 的记录，返回删除的条数
+	PruneRecords(before time.Time) (int, error)
+
+	// Cache 返回 手机号 -> ticket
+	Cache() (map[string]Ticket, error)
+	// PutCache 写入或覆盖单个手机号的 ticket
+	PutCache(phone string, t Ticket) error
+	// DeleteCache 删除单个手机号的 ticket
+	DeleteCache(phone string) error
+
+	// LoginStates 返回 手机号 -> 登录失败记录
+	LoginStates() (map[string]LoginState, error)
+	// PutLoginState 写入或覆盖单个手机号的登录失败记录
+	PutLoginState(phone string, s LoginState) error
+	// DeleteLoginState 删除单个手机号的登录失败记录
+	DeleteLoginState(phone string) error
+
+	// SaveRun 按 ID 新增或更新一次运行
+	SaveRun(r Run) error
+	// Runs 按开始时间返回运行历史
+	Runs() ([]Run, error)
+
+	// Meta 读取元数据，不存在时返回空串
+	Meta(key string) (string, error)
+	SetMeta(key, value string) error
+
+	Close() error
+}
+
+// Open : 打开 dir 下指定后端的存储，backend 为空时使用 JSON
+func Open(backend, dir string) (Store, error) {
+	switch backend {
+	case "", BackendJSON:
+		return OpenJSON(dir), nil
+	case BackendSQLite:
+		return OpenSQLite(dir)
+	default:
+		return nil, fmt.Errorf("未知的存储后端 %q，可选 %s / %s", backend, BackendJSON, BackendSQLite)
+	}
+}
+
+// NewRunID : 以开始时间生成运行 ID
+func NewRunID(t time.Time) string {
+	return t.Format("20060102T150405.000")
+}
@@ -0,0 +1,221 @@
+// store_test.go
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"HighFrequencyTrading/ledger"
+)
+
+func openBoth(t *testing.T) map[string]Store {
+	t.Helper()
+	res := make(map[string]Store)
+	for _, backend := range []string{BackendJSON, BackendSQLite} {
+		st, err := Open(backend, t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { st.Close() })
+		res[backend] = st
+	}
+	return res
+}
+
+func TestStoreBackends(t *testing.T) {
+	for backend, st := range openBoth(t) {
+		t.Run(backend, func(t *testing.T) {
+			now := time.Now().Truncate(time.Millisecond)
+			want := ledger.Record{Time: now, Session: "10", Phone: "13800138000", Item: "5元话费",
+				ActivityID: "a5", Outcome: ledger.OutcomeSuccess, HTTPStatus: 200, Code: "0", Message: "ok", LatencyMs: 12}
+			if err := st.Append(want); err != nil {
+				t.Fatal(err)
+			}
+			records, err := st.Records()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(records) != 1 || !records[0].Time.Equal(now) || records[0].Code != "0" || records[0].LatencyMs != 12 {
+				t.Errorf("记录不符: %+v", records)
+			}
+
+			if err := st.PutCache("13800138000", Ticket{Ticket: "t1", IssuedAt: now}); err != nil {
+				t.Fatal(err)
+			}
+			if err := st.PutCache("13800138000", Ticket{Ticket: "t2", IssuedAt: now}); err != nil {
+				t.Fatal(err)
+			}
+			if err := st.PutCache("13900139000", Ticket{Ticket: "t3", IssuedAt: now}); err != nil {
+				t.Fatal(err)
+			}
+			if err := st.DeleteCache("13900139000"); err != nil {
+				t.Fatal(err)
+			}
+			c, err := st.Cache()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(c) != 1 || c["13800138000"].Ticket != "t2" || !c["13800138000"].IssuedAt.Equal(now) {
+				t.Errorf("缓存不符: %v", c)
+			}
+
+			ls := LoginState{Failures: 2, CredentialFailures: 1, LastError: "密码错误", LastFailure: now,
+				NextAttempt: now.Add(10 * time.Minute), Fingerprint: "fp"}
+			if err := st.PutLoginState("13800138000", ls); err != nil {
+				t.Fatal(err)
+			}
+			if err := st.PutLoginState("13900139000", ls); err != nil {
+				t.Fatal(err)
+			}
+			if err := st.DeleteLoginState("13900139000"); err != nil {
+				t.Fatal(err)
+			}
+			states, err := st.LoginStates()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := states["13800138000"]
+			if len(states) != 1 || got.Failures != 2 || !got.NextAttempt.Equal(ls.NextAttempt) || got.Suspended() || got.Fingerprint != "fp" {
+				t.Errorf("登录失败记录不符: %+v", states)
+			}
+
+			run := Run{ID: NewRunID(now), Profile: "default", Session: "10", StartedAt: now, Accounts: 2}
+			if err := st.SaveRun(run); err != nil {
+				t.Fatal(err)
+			}
+			run.FinishedAt, run.Attempts, run.Successes = now.Add(time.Minute), 3, 1
+			if err := st.SaveRun(run); err != nil {
+				t.Fatal(err)
+			}
+			runs, err := st.Runs()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(runs) != 1 || runs[0].Attempts != 3 || runs[0].FinishedAt.IsZero() {
+				t.Errorf("运行历史不符: %+v", runs)
+			}
+
+			if v, err := st.Meta("k"); err != nil || v != "" {
+				t.Errorf("不存在的元数据应为空: %q, %v", v, err)
+			}
+			if err := st.SetMeta("k", "v"); err != nil {
+				t.Fatal(err)
+			}
+			if v, _ := st.Meta("k"); v != "v" {
+				t.Errorf("元数据 = %q, 期望 v", v)
+			}
+		})
+	}
+}
+
+func TestImportLegacyIntoSQLite(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(LegacyExchangeLogFile, `{"202502":{"5元话费":["13800138000"]},"202503":{"10元话费":["13800138000"]}}`)
+	write(CacheFile, `{"13800138000":"ticket"}`)
+
+	// JSON 账本中已有 202503 的成功记录，不应与旧日志重复
+	jl := ledger.Open(filepath.Join(dir, LedgerFile))
+	march := time.Date(2025, 3, 1, 10, 0, 0, 0, time.Local)
+	jl.Append(ledger.Record{Time: march, Session: "10", Phone: "13800138000", Item: "10元话费", Outcome: ledger.OutcomeSuccess})
+	jl.Append(ledger.Record{Time: march, Session: "10", Phone: "13800138000", Item: "5元话费", Outcome: ledger.OutcomeFailed})
+
+	st, err := OpenSQLite(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+
+	res, err := ImportLegacy(st, dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 账本 2 条 + 旧日志中仅 202502 的 1 条
+	records, _ := st.Records()
+	if res.Records != 3 || len(records) != 3 || res.Tickets != 1 {
+		t.Errorf("导入统计不符: %+v, 实际记录 %d", res, len(records))
+	}
+	if months := ledger.MonthView(records); len(months["202502"]["5元话费"]) != 1 || len(months["202503"]["10元话费"]) != 1 {
+		t.Errorf("月视图不符: %v", months)
+	}
+
+	// 旧缓存的值是字符串，签发时间未知
+	if c, _ := st.Cache(); c["13800138000"].Ticket != "ticket" || !c["13800138000"].IssuedAt.IsZero() {
+		t.Errorf("旧缓存导入不符: %+v", c)
+	}
+
+	again, err := ImportLegacy(st, dir, false)
+	if err != nil || !again.Skipped {
+		t.Errorf("第二次导入应跳过: %+v, %v", again, err)
+	}
+	forced, err := ImportLegacy(st, dir, true)
+	if err != nil || forced.Records != 0 || forced.Tickets != 0 {
+		t.Errorf("强制导入不应产生重复: %+v, %v", forced, err)
+	}
+}
+
+func TestTicketExpired(t *testing.T) {
+	now := time.Now()
+	fresh := Ticket{Ticket: "a", IssuedAt: now.Add(-time.Hour)}
+	if fresh.Expired(now, 2*time.Hour) || !fresh.Expired(now, 30*time.Minute) {
+		t.Error("按 TTL 判断过期不符")
+	}
+	if (Ticket{Ticket: "a"}).Expired(now, time.Minute) {
+		t.Error("签发时间未知的 ticket 不应按 TTL 判定过期")
+	}
+}
+
+func TestApplyRetention(t *testing.T) {
+	for backend, st := range openBoth(t) {
+		t.Run(backend, func(t *testing.T) {
+			archiveDir := filepath.Join(t.TempDir(), ArchiveDir)
+			now := time.Date(2025, 6, 15, 12, 0, 0, 0, time.Local)
+			for _, month := range []time.Month{1, 2, 3, 4, 5, 6} {
+				r := ledger.Record{Time: time.Date(2025, month, 10, 10, 0, 0, 0, time.Local), Phone: "13800138000", Item: "5元话费", Outcome: ledger.OutcomeSuccess}
+				if err := st.Append(r); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// 保留 4、5、6 月；1、2、3 月归档
+			res, err := ApplyRetention(st, archiveDir, 3, 0, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Archived != 3 || len(res.ArchivedMonths) != 3 {
+				t.Errorf("归档结果不符: %+v", res)
+			}
+			records, _ := st.Records()
+			if len(records) != 3 || records[0].Month() != "202504" {
+				t.Errorf("账本中应只剩最近 3 个月: %+v", records)
+			}
+			months, _ := ArchivedMonths(archiveDir)
+			if len(months) != 3 || months[0] != "202501" {
+				t.Errorf("归档月份不符: %v", months)
+			}
+
+			// 重复执行不应产生重复归档
+			if res, err := ApplyRetention(st, archiveDir, 3, 0, now); err != nil || res.Archived != 0 {
+				t.Errorf("重复执行结果不符: %+v, %v", res, err)
+			}
+
+			// 最多保留 5 个月：删除 1 月的归档
+			res, err = ApplyRetention(st, archiveDir, 3, 5, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Pruned != 1 || len(res.PrunedMonths) != 1 || res.PrunedMonths[0] != "202501" {
+				t.Errorf("清理结果不符: %+v", res)
+			}
+			if archived, _ := ArchivedRecords(archiveDir, "202502"); len(archived) != 1 {
+				t.Errorf("202502 归档应保留: %v", archived)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// WriteFileAtomic 先写同目录下的临时文件并 fsync，再 rename 覆盖目标文件，
+// 进程中途崩溃时目标文件要么是旧内容，要么是完整的新内容
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	p, err := StageFile(path, data, perm)
+	if err != nil {
+		return err
+	}
+	defer p.Discard()
+	return p.Commit()
+}
+
+// PendingFile 已写好并 fsync 的临时文件，Commit 时才替换目标文件，
+// 用于在其它数据写入成功后再落盘
+type PendingFile struct {
+	tmp, path string
+}
+
+// StageFile 把 data 写入 path 同目录下的临时文件，目标文件保持不变
+func StageFile(path string, data []byte, perm os.FileMode) (*PendingFile, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return nil, err
+	}
+	err = func() error {
+		if _, err := tmp.Write(data); err != nil {
+			return err
+		}
+		if err := tmp.Chmod(perm); err != nil {
+			return err
+		}
+		return tmp.Sync()
+	}()
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+	return &PendingFile{tmp: tmp.Name(), path: path}, nil
+}
+
+// Commit rename 临时文件覆盖目标文件
+func (p *PendingFile) Commit() error {
+	if err := os.Rename(p.tmp, p.path); err != nil {
+		return err
+	}
+	p.tmp = ""
+	return syncDir(filepath.Dir(p.path))
+}
+
+// Discard 删除未提交的临时文件，已提交时不做任何事
+func (p *PendingFile) Discard() {
+	if p.tmp != "" {
+		os.Remove(p.tmp)
+		p.tmp = ""
+	}
+}
+
+// syncDir 刷新目录项，确保 rename 本身落盘；部分平台不支持对目录 fsync，忽略该错误
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return nil
+	}
+	defer d.Close()
+	_ = d.Sync()
+	return nil
+}
@@ -0,0 +1,114 @@
+// atomic_test.go
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+	if err := os.WriteFile(path, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFileAtomic(path, []byte("new"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "new" {
+		t.Fatalf("内容 = %q, %v", data, err)
+	}
+	if st, _ := os.Stat(path); st.Mode().Perm() != 0600 {
+		t.Errorf("权限 = %v, 期望 0600", st.Mode().Perm())
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("临时文件未清理: %v", entries)
+	}
+}
+
+func TestLockSerializesReadModifyWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "counter")
+
+	// flock 以打开的文件为单位，同一进程内的多个 Lock 之间同样互斥
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lock, err := Lock(path)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer lock.Unlock()
+			data, _ := os.ReadFile(path)
+			n, _ := strconv.Atoi(string(data))
+			if err := WriteFileAtomic(path, []byte(strconv.Itoa(n+1)), 0644); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	data, _ := os.ReadFile(path)
+	if string(data) != "20" {
+		t.Errorf("计数 = %s, 期望 20", data)
+	}
+}
+
+func TestStageFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "vault.json")
+	if err := os.WriteFile(path, []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	p, err := StageFile(path, []byte("new"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "old" {
+		t.Fatalf("提交前不应改动目标文件: %q", data)
+	}
+	p.Discard()
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("放弃后应删除临时文件: %v", entries)
+	}
+
+	if p, err = StageFile(path, []byte("new"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	p.Discard()
+	if data, _ := os.ReadFile(path); string(data) != "new" {
+		t.Errorf("提交后内容 = %q", data)
+	}
+}
+
+func TestTryLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vault.json")
+	a, err := LockShared(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := LockShared(path)
+	if err != nil {
+		t.Fatalf("共享锁应可同时持有: %v", err)
+	}
+	if _, err := TryLock(path); err != ErrLocked {
+		t.Fatalf("持有共享锁时 TryLock 应返回 ErrLocked: %v", err)
+	}
+	a.Unlock()
+	b.Unlock()
+	l, err := TryLock(path)
+	if err != nil {
+		t.Fatalf("释放后应取得排他锁: %v", err)
+	}
+	l.Unlock()
+}
@@ -0,0 +1,55 @@
+package util
+
+import (
+	"errors"
+	"os"
+)
+
+// ErrLocked TryLock 时锁已被其他进程持有
+var ErrLocked = errors.New("已被其他进程锁定")
+
+// FileLock 基于 <path>.lock 的进程间建议锁。
+// 锁加在独立的 .lock 文件上，目标文件被 rename 替换后锁依然有效
+type FileLock struct {
+	f *os.File
+}
+
+// Lock 阻塞直到取得 path 对应的排他锁
+func Lock(path string) (*FileLock, error) {
+	return lock(path, false, true)
+}
+
+// LockShared 阻塞直到取得 path 对应的共享锁，可与其他共享锁同时持有，与排他锁互斥
+func LockShared(path string) (*FileLock, error) {
+	return lock(path, true, true)
+}
+
+// TryLock 尝试取得 path 对应的排他锁，已被其他进程持有（含共享锁）时立即返回 ErrLocked
+func TryLock(path string) (*FileLock, error) {
+	return lock(path, false, false)
+}
+
+func lock(path string, shared, wait bool) (*FileLock, error) {
+	f, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f, shared, wait); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &FileLock{f: f}, nil
+}
+
+// Unlock 释放锁，.lock 文件保留以免与其他进程竞争删除
+func (l *FileLock) Unlock() error {
+	if l == nil || l.f == nil {
+		return nil
+	}
+	err := unlockFile(l.f)
+	if cerr := l.f.Close(); err == nil {
+		err = cerr
+	}
+	l.f = nil
+	return err
+}
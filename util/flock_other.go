@@ -0,0 +1,21 @@
+//go:build !unix && !windows
+
+package util
+
+import (
+	"log"
+	"os"
+	"sync"
+)
+
+var warnNoLock sync.Once
+
+// 其它平台没有可用的文件锁，只能依赖进程内互斥；提示用户不要同时运行多个实例
+func lockFile(f *os.File, shared, wait bool) error {
+	warnNoLock.Do(func() {
+		log.Println("[Warn] 当前平台不支持文件锁，请勿同时运行多个实例，否则账本与缓存可能交错写入")
+	})
+	return nil
+}
+
+func unlockFile(f *os.File) error { return nil }